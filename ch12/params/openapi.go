@@ -0,0 +1,187 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package params
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// A Parameter is an OpenAPI 3 Parameter Object describing
+// one field of a struct passed to Unpack.
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required,omitempty"`
+	Schema   *Schema `json:"schema"`
+}
+
+// A Schema is the subset of an OpenAPI 3 Schema Object
+// needed to describe the parameter types supported by Unpack.
+type Schema struct {
+	Type      string        `json:"type"`
+	Items     *Schema       `json:"items,omitempty"`
+	Default   interface{}   `json:"default,omitempty"`
+	Enum      []interface{} `json:"enum,omitempty"`
+	Minimum   *int64        `json:"minimum,omitempty"`
+	Maximum   *int64        `json:"maximum,omitempty"`
+	MinLength *int64        `json:"minLength,omitempty"`
+	MaxLength *int64        `json:"maxLength,omitempty"`
+	Pattern   string        `json:"pattern,omitempty"`
+}
+
+// Parameters returns the OpenAPI query and path parameters described
+// by the struct pointed to by ptr.  Parameter names follow the http
+// tag rules of Unpack, and non-zero field values, typically defaults
+// set before calling Unpack, are reported as schema defaults.
+//
+// An optional validate tag documents constraints on a field, as
+// a comma-separated list of the following options:
+//
+//	required      the parameter must be present
+//	path          the parameter is a path parameter, and so required
+//	min=N, max=N  bounds on an int, or on the length of a string
+//	pattern=RE    a regular expression that a string must match
+//	enum=A|B|C    the permitted values
+//
+// Unpack does not enforce these constraints.
+// Other fields are described as query parameters, even though
+// UnpackPath may also populate them from a JSON or form body;
+// Parameters does not describe request bodies.
+// Unexported fields and multipart file fields are not parameters
+// and are omitted.
+func Parameters(ptr interface{}) ([]Parameter, error) {
+	v, err := structOf(ptr) // the struct variable
+	if err != nil {
+		return nil, err
+	}
+	var params []Parameter
+	for i := 0; i < v.NumField(); i++ {
+		fieldInfo := v.Type().Field(i)
+		f := v.Field(i)
+		if fieldInfo.PkgPath != "" {
+			continue // unexported
+		}
+		if f.Type() == fileHeaderType ||
+			f.Kind() == reflect.Slice && f.Type().Elem() == fileHeaderType {
+			continue
+		}
+		name, _ := fieldName(fieldInfo)
+		p := Parameter{Name: name, In: "query"}
+		elemType := f.Type()
+		schema, err := schemaFor(elemType)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+		p.Schema = schema
+		if f.Kind() == reflect.Slice {
+			elemType = f.Type().Elem()
+			schema = schema.Items
+		}
+		if err := validate(&p, schema, elemType, fieldInfo.Tag.Get("validate")); err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+		if !f.IsZero() {
+			p.Schema.Default = f.Interface()
+		}
+		params = append(params, p)
+	}
+	return params, nil
+}
+
+// ParametersHandler returns an HTTP handler that serves the
+// parameter list of the struct pointed to by ptr as JSON.
+func ParametersHandler(ptr interface{}) http.Handler {
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		params, err := Parameters(ptr)
+		if err != nil {
+			http.Error(resp, err.Error(), http.StatusInternalServerError) // 500
+			return
+		}
+		resp.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(resp)
+		enc.SetIndent("", "  ")
+		enc.Encode(params)
+	})
+}
+
+// schemaFor returns the schema of parameters of type t.
+func schemaFor(t reflect.Type) (*Schema, error) {
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}, nil
+	case reflect.Int:
+		return &Schema{Type: "integer"}, nil
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}, nil
+	case reflect.Slice:
+		items, err := schemaFor(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return &Schema{Type: "array", Items: items}, nil
+	default:
+		return nil, fmt.Errorf("unsupported kind %s", t)
+	}
+}
+
+// validate records in p and schema the constraints in tag,
+// the validate tag of a field whose (element) type is t.
+func validate(p *Parameter, schema *Schema, t reflect.Type, tag string) error {
+	if tag == "" {
+		return nil
+	}
+	for _, opt := range strings.Split(tag, ",") {
+		key, value := opt, ""
+		if i := strings.IndexByte(opt, '='); i >= 0 {
+			key, value = opt[:i], opt[i+1:]
+		}
+		switch key {
+		case "required":
+			p.Required = true
+
+		case "path":
+			p.In = "path"
+			p.Required = true
+
+		case "min", "max":
+			n, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return fmt.Errorf("validate %s: %v", key, err)
+			}
+			switch {
+			case t.Kind() == reflect.Int && key == "min":
+				schema.Minimum = &n
+			case t.Kind() == reflect.Int:
+				schema.Maximum = &n
+			case t.Kind() == reflect.String && key == "min":
+				schema.MinLength = &n
+			case t.Kind() == reflect.String:
+				schema.MaxLength = &n
+			default:
+				return fmt.Errorf("validate %s: unsupported kind %s", key, t)
+			}
+
+		case "pattern":
+			schema.Pattern = value
+
+		case "enum":
+			for _, s := range strings.Split(value, "|") {
+				elem := reflect.New(t).Elem()
+				if err := populate(elem, s); err != nil {
+					return fmt.Errorf("validate enum: %v", err)
+				}
+				schema.Enum = append(schema.Enum, elem.Interface())
+			}
+
+		default:
+			return fmt.Errorf("unknown validate option %q", key)
+		}
+	}
+	return nil
+}
@@ -7,40 +7,125 @@
 package params
 
 import (
+	"encoding/json"
 	"fmt"
+	"mime"
+	"mime/multipart"
 	"net/http"
+	"net/url"
 	"reflect"
 	"strconv"
 	"strings"
 )
 
+// maxMemory is the number of bytes of a multipart body held in
+// memory; the remainder of any file parts is stored on disk.
+const maxMemory = 32 << 20
+
 //!+Unpack
 
 // Unpack populates the fields of the struct pointed to by ptr
 // from the HTTP request parameters in req.
 func Unpack(req *http.Request, ptr interface{}) error {
-	if err := req.ParseForm(); err != nil {
-		return err
-	}
+	return UnpackPath(req, nil, ptr)
+}
+
+//!-Unpack
 
+// UnpackPath is like Unpack, but also populates fields from the
+// path parameters extracted from the request URL by a router.
+//
+// Parameters are taken from three sources, in increasing order of
+// precedence: the request body, the URL query, and the path.
+// A parameter present in a higher source replaces any value, or all
+// slice elements, set by a lower one.
+//
+// The body is decoded according to its Content-Type:
+// a JSON object is decoded field by field into the struct;
+// multipart/form-data and application/x-www-form-urlencoded forms
+// are decoded like the query, and multipart files populate fields
+// of type *multipart.FileHeader or []*multipart.FileHeader.
+func UnpackPath(req *http.Request, path map[string]string, ptr interface{}) error {
 	// Build map of fields keyed by effective name.
-	fields := make(map[string]reflect.Value)
+	b := binding{
+		fields: make(map[string]reflect.Value),
+		seen:   make(map[string]bool),
+	}
 	v := reflect.ValueOf(ptr).Elem() // the struct variable
 	for i := 0; i < v.NumField(); i++ {
-		fieldInfo := v.Type().Field(i) // a reflect.StructField
-		tag := fieldInfo.Tag           // a reflect.StructTag
-		name := tag.Get("http")
-		if name == "" {
-			name = strings.ToLower(fieldInfo.Name)
+		name, _ := fieldName(v.Type().Field(i))
+		b.fields[name] = v.Field(i)
+	}
+
+	// Decode the body.
+	ctype, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	switch ctype {
+	case "application/json":
+		if err := b.json(req); err != nil {
+			return err
+		}
+	case "multipart/form-data":
+		if err := req.ParseMultipartForm(maxMemory); err != nil {
+			return err
+		}
+		if err := b.values(req.MultipartForm.Value); err != nil {
+			return err
+		}
+		if err := b.files(req.MultipartForm.File); err != nil {
+			return err
+		}
+	default:
+		if err := req.ParseForm(); err != nil {
+			return err
+		}
+		if err := b.values(req.PostForm); err != nil {
+			return err
 		}
-		fields[name] = v.Field(i)
 	}
 
-	// Update struct field for each parameter in the request.
-	for name, values := range req.Form {
-		f := fields[name]
+	// Then the query and path parameters.
+	query, err := url.ParseQuery(req.URL.RawQuery)
+	if err != nil {
+		return err
+	}
+	if err := b.values(query); err != nil {
+		return err
+	}
+	pathValues := make(url.Values)
+	for name, value := range path {
+		pathValues.Set(name, value)
+	}
+	return b.values(pathValues)
+}
+
+// A binding records the fields of a struct being unpacked,
+// keyed by effective name, and the names already populated.
+type binding struct {
+	fields map[string]reflect.Value
+	seen   map[string]bool
+}
+
+// field returns the field for the named parameter, or the zero
+// Value if there is none.  A slice field populated by an earlier
+// source is cleared, so that later sources replace it.
+func (b *binding) field(name string) reflect.Value {
+	f := b.fields[name]
+	if !f.IsValid() {
+		return f // ignore unrecognized HTTP parameters
+	}
+	if b.seen[name] && f.Kind() == reflect.Slice {
+		f.Set(reflect.Zero(f.Type()))
+	}
+	b.seen[name] = true
+	return f
+}
+
+// values updates the struct field for each parameter in vals.
+func (b *binding) values(vals url.Values) error {
+	for name, values := range vals {
+		f := b.field(name)
 		if !f.IsValid() {
-			continue // ignore unrecognized HTTP parameters
+			continue
 		}
 		for _, value := range values {
 			if f.Kind() == reflect.Slice {
@@ -59,7 +144,44 @@ func Unpack(req *http.Request, ptr interface{}) error {
 	return nil
 }
 
-//!-Unpack
+// json decodes the JSON object in the request body, updating
+// the struct field for each of its members.
+func (b *binding) json(req *http.Request) error {
+	var members map[string]json.RawMessage
+	if err := json.NewDecoder(req.Body).Decode(&members); err != nil {
+		return fmt.Errorf("decoding JSON body: %v", err)
+	}
+	for name, data := range members {
+		f := b.field(name)
+		if !f.IsValid() {
+			continue
+		}
+		if err := json.Unmarshal(data, f.Addr().Interface()); err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+var fileHeaderType = reflect.TypeOf((*multipart.FileHeader)(nil))
+
+// files updates the struct field for each file in a multipart form.
+func (b *binding) files(files map[string][]*multipart.FileHeader) error {
+	for name, headers := range files {
+		f := b.field(name)
+		switch {
+		case !f.IsValid() || len(headers) == 0:
+			continue
+		case f.Type() == fileHeaderType:
+			f.Set(reflect.ValueOf(headers[0]))
+		case f.Kind() == reflect.Slice && f.Type().Elem() == fileHeaderType:
+			f.Set(reflect.ValueOf(headers))
+		default:
+			return fmt.Errorf("%s: file upload to unsupported kind %s", name, f.Type())
+		}
+	}
+	return nil
+}
 
 //!+populate
 func populate(v reflect.Value, value string) error {
@@ -88,3 +210,107 @@ func populate(v reflect.Value, value string) error {
 }
 
 //!-populate
+
+// fieldName returns the effective parameter name of the struct field f,
+// and reports whether its http tag carries the omitempty option.
+// The tag has the form `http:"name,omitempty"`; an empty name
+// defaults to the lowercase field name.
+func fieldName(f reflect.StructField) (name string, omitempty bool) {
+	tag := f.Tag.Get("http")
+	if i := strings.IndexByte(tag, ','); i >= 0 {
+		omitempty = tag[i+1:] == "omitempty"
+		tag = tag[:i]
+	}
+	if tag == "" {
+		tag = strings.ToLower(f.Name)
+	}
+	return tag, omitempty
+}
+
+// structOf returns the struct that ptr points to, or ptr itself if
+// it is a struct.  It reports an error for a nil pointer or any
+// other type.
+func structOf(ptr interface{}) (reflect.Value, error) {
+	v := reflect.ValueOf(ptr)
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return reflect.Value{}, fmt.Errorf("nil %T", ptr)
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return reflect.Value{}, fmt.Errorf("unsupported type %T", ptr)
+	}
+	return v, nil
+}
+
+// Pack is the inverse of Unpack: it returns the URL parameters
+// encoded by the fields of the struct pointed to by ptr.
+// Slice fields yield one parameter per element, and fields tagged
+// omitempty are skipped when they hold the zero value.
+func Pack(ptr interface{}) (url.Values, error) {
+	vals := make(url.Values)
+	v, err := structOf(ptr) // the struct variable
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < v.NumField(); i++ {
+		name, omitempty := fieldName(v.Type().Field(i))
+		f := v.Field(i)
+		if omitempty && f.IsZero() {
+			continue
+		}
+		if f.Kind() == reflect.Slice {
+			for j := 0; j < f.Len(); j++ {
+				s, err := format(f.Index(j))
+				if err != nil {
+					return nil, fmt.Errorf("%s: %v", name, err)
+				}
+				vals.Add(name, s)
+			}
+		} else {
+			s, err := format(f)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", name, err)
+			}
+			vals.Add(name, s)
+		}
+	}
+	return vals, nil
+}
+
+// URL returns base with the parameters packed from the struct
+// pointed to by ptr added to its query string.
+func URL(base string, ptr interface{}) (string, error) {
+	u, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+	vals, err := Pack(ptr)
+	if err != nil {
+		return "", err
+	}
+	q := u.Query()
+	for name, values := range vals {
+		q[name] = values
+	}
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// format is the inverse of populate.
+func format(v reflect.Value) (string, error) {
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+
+	case reflect.Int:
+		return strconv.FormatInt(v.Int(), 10), nil
+
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+
+	default:
+		return "", fmt.Errorf("unsupported kind %s", v.Type())
+	}
+}
@@ -0,0 +1,246 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package params
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type search struct {
+	Labels     []string `http:"l"`
+	MaxResults int      `http:"max"`
+	Exact      bool     `http:"x,omitempty"`
+	Query      string   `http:",omitempty"`
+}
+
+func ExampleURL() {
+	data := search{Labels: []string{"golang", "programming"}, MaxResults: 10}
+	u, err := URL("http://localhost:12345/search", &data)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println(u)
+	// Output:
+	// http://localhost:12345/search?l=golang&l=programming&max=10
+}
+
+func TestPackUnpack(t *testing.T) {
+	for _, test := range []search{
+		{MaxResults: 10},
+		{Labels: []string{"a", "b c", "&"}, MaxResults: -1, Exact: true},
+		{Labels: []string{""}, Query: "hello, world"},
+	} {
+		u, err := URL("/search", &test)
+		if err != nil {
+			t.Errorf("URL(%+v): %v", test, err)
+			continue
+		}
+		var got search
+		if err := Unpack(httptest.NewRequest("GET", u, nil), &got); err != nil {
+			t.Errorf("Unpack(%s): %v", u, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test) {
+			t.Errorf("Unpack(URL(%+v)) = %+v", test, got)
+		}
+	}
+}
+
+func TestPackUnsupported(t *testing.T) {
+	var data struct{ F float64 }
+	for _, ptr := range []interface{}{&data, nil, (*search)(nil), 42} {
+		if _, err := Pack(ptr); err == nil {
+			t.Errorf("Pack(%#v) succeeded, want error", ptr)
+		}
+	}
+}
+
+func TestUnpackJSON(t *testing.T) {
+	body := `{"l": ["a", "b"], "max": 5, "x": true, "unknown": null}`
+	req := httptest.NewRequest("POST", "/search?max=20", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	var got search
+	if err := Unpack(req, &got); err != nil {
+		t.Fatal(err)
+	}
+	want := search{Labels: []string{"a", "b"}, MaxResults: 20, Exact: true}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Unpack = %+v, want %+v", got, want)
+	}
+
+	req = httptest.NewRequest("POST", "/search", strings.NewReader(`{"max": "lots"}`))
+	req.Header.Set("Content-Type", "application/json")
+	if err := Unpack(req, &got); err == nil || !strings.HasPrefix(err.Error(), "max: ") {
+		t.Errorf("Unpack with bad JSON member: got error %v, want max: ...", err)
+	}
+}
+
+func TestUnpackMultipart(t *testing.T) {
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	w.WriteField("l", "body")
+	w.WriteField("id", "body")
+	fw, _ := w.CreateFormFile("file", "hello.txt")
+	fw.Write([]byte("hello, world"))
+	w.Close()
+
+	req := httptest.NewRequest("POST", "/upload/7?l=q1&l=q2&id=query", &buf)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	var got struct {
+		ID     string   `http:"id"`
+		Labels []string `http:"l"`
+		File   *multipart.FileHeader
+	}
+	if err := UnpackPath(req, map[string]string{"id": "7"}, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != "7" {
+		t.Errorf("ID = %q, want path value %q", got.ID, "7")
+	}
+	if want := []string{"q1", "q2"}; !reflect.DeepEqual(got.Labels, want) {
+		t.Errorf("Labels = %q, want query values %q", got.Labels, want)
+	}
+	if got.File == nil {
+		t.Fatal("File = nil")
+	}
+	f, err := got.File.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if data, _ := ioutil.ReadAll(f); string(data) != "hello, world" {
+		t.Errorf("File contents = %q", data)
+	}
+}
+
+func ExampleParametersHandler() {
+	var data struct {
+		Labels     []string `http:"l" validate:"enum=golang|programming"`
+		MaxResults int      `http:"max" validate:"min=1,max=100"`
+		Exact      bool     `http:"x"`
+		Query      string   `http:"q" validate:"required,min=1"`
+	}
+	data.MaxResults = 10 // set default
+
+	resp := httptest.NewRecorder()
+	ParametersHandler(&data).ServeHTTP(resp, httptest.NewRequest("GET", "/search/parameters", nil))
+	fmt.Print(resp.Body)
+	// Output:
+	// [
+	//   {
+	//     "name": "l",
+	//     "in": "query",
+	//     "schema": {
+	//       "type": "array",
+	//       "items": {
+	//         "type": "string",
+	//         "enum": [
+	//           "golang",
+	//           "programming"
+	//         ]
+	//       }
+	//     }
+	//   },
+	//   {
+	//     "name": "max",
+	//     "in": "query",
+	//     "schema": {
+	//       "type": "integer",
+	//       "default": 10,
+	//       "minimum": 1,
+	//       "maximum": 100
+	//     }
+	//   },
+	//   {
+	//     "name": "x",
+	//     "in": "query",
+	//     "schema": {
+	//       "type": "boolean"
+	//     }
+	//   },
+	//   {
+	//     "name": "q",
+	//     "in": "query",
+	//     "required": true,
+	//     "schema": {
+	//       "type": "string",
+	//       "minLength": 1
+	//     }
+	//   }
+	// ]
+}
+
+func TestParametersPath(t *testing.T) {
+	var data struct {
+		ID    string `validate:"path"`
+		Query string `http:"q"`
+	}
+	params, err := Parameters(&data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		name, in string
+		required bool
+	}{
+		{"id", "path", true},
+		{"q", "query", false},
+	} {
+		var p *Parameter
+		for i := range params {
+			if params[i].Name == test.name {
+				p = &params[i]
+			}
+		}
+		if p == nil {
+			t.Errorf("no parameter %q", test.name)
+		} else if p.In != test.in || p.Required != test.required {
+			t.Errorf("parameter %q: in %q, required %t; want %q, %t",
+				test.name, p.In, p.Required, test.in, test.required)
+		}
+	}
+}
+
+func TestParametersUnexported(t *testing.T) {
+	data := struct {
+		Query  string `http:"q"`
+		hidden int
+	}{hidden: 1}
+	params, err := Parameters(&data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(params) != 1 || params[0].Name != "q" {
+		t.Errorf("Parameters = %+v, want only q", params)
+	}
+}
+
+func TestParametersErrors(t *testing.T) {
+	for _, ptr := range []interface{}{
+		nil,
+		(*struct{ S string })(nil),
+		&struct{ F float64 }{},
+		&struct {
+			B bool `validate:"min=1"`
+		}{},
+		&struct {
+			N int `validate:"enum=1|two"`
+		}{},
+		&struct {
+			S string `validate:"bogus"`
+		}{},
+	} {
+		if _, err := Parameters(ptr); err == nil {
+			t.Errorf("Parameters(%T) succeeded, want error", ptr)
+		}
+	}
+}
@@ -23,6 +23,10 @@
 //!+
 
 // Package bzip provides a writer that uses bzip2 compression (bzip.org).
+//!-
+//
+// It also provides a reader for bzip2-compressed streams.
+//!+
 package bzip
 
 /*
@@ -38,43 +42,127 @@ void bz2free(bz_stream* s) { free(s); }
 import "C"
 
 import (
+	"fmt"
 	"io"
 	"unsafe"
 )
 
-type writer struct {
+// A Writer compresses the data written to it.
+type Writer struct {
 	w      io.Writer // underlying output stream
 	stream *C.bz_stream
 	outbuf [64 * 1024]byte
+	opts   WriterOptions
+	err    error // sticky error from libbzip2
 }
 
 // NewWriter returns a writer for bzip2-compressed streams.
-func NewWriter(out io.Writer) io.WriteCloser {
-	const blockSize = 9
-	const verbosity = 0
-	const workFactor = 30
-	w := &writer{w: out, stream: C.bz2alloc()}
-	C.BZ2_bzCompressInit(w.stream, blockSize, verbosity, workFactor)
+func NewWriter(out io.Writer) *Writer {
+	w := &Writer{w: out, stream: C.bz2alloc()}
+	w.err = w.init(DefaultWriterOptions)
 	return w
 }
 
 //!-
 
+// WriterOptions holds the compression parameters of a writer.
+// See the libbzip2 manual for BZ2_bzCompressInit.
+type WriterOptions struct {
+	// BlockSize is the block size in units of 100k, from 1
+	// (fastest, least memory) to 9 (best compression).
+	BlockSize int
+
+	// WorkFactor, from 1 to 250, controls how hard the compressor
+	// tries before falling back to a slower algorithm for highly
+	// repetitive input.  Zero means the libbzip2 default, 30.
+	WorkFactor int
+
+	// Verbosity, from 0 to 4, is the amount of diagnostic output
+	// that libbzip2 writes to the standard error.
+	Verbosity int
+}
+
+// DefaultWriterOptions are the options used by NewWriter.
+var DefaultWriterOptions = WriterOptions{BlockSize: 9, WorkFactor: 30}
+
+// NewWriterLevel is like NewWriter but uses the specified compression
+// level, from 1 (fastest) to 9 (best compression), which determines
+// the block size.
+func NewWriterLevel(out io.Writer, level int) (*Writer, error) {
+	if level < 1 || level > 9 {
+		return nil, fmt.Errorf("bzip: invalid compression level %d", level)
+	}
+	opts := DefaultWriterOptions
+	opts.BlockSize = level
+	return NewWriterOptions(out, opts)
+}
+
+// NewWriterOptions is like NewWriter but uses the specified options.
+func NewWriterOptions(out io.Writer, opts WriterOptions) (*Writer, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	w := &Writer{w: out, stream: C.bz2alloc()}
+	if err := w.init(opts); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// validate reports an error if any option is out of range.
+func (opts WriterOptions) validate() error {
+	if opts.BlockSize < 1 || opts.BlockSize > 9 {
+		return fmt.Errorf("bzip: invalid block size %d", opts.BlockSize)
+	}
+	if opts.WorkFactor < 0 || opts.WorkFactor > 250 {
+		return fmt.Errorf("bzip: invalid work factor %d", opts.WorkFactor)
+	}
+	if opts.Verbosity < 0 || opts.Verbosity > 4 {
+		return fmt.Errorf("bzip: invalid verbosity %d", opts.Verbosity)
+	}
+	return nil
+}
+
+// init initializes the compressor.  On failure it frees the
+// stream, so that the writer is closed.
+func (w *Writer) init(opts WriterOptions) error {
+	w.opts = opts
+	ret := C.BZ2_bzCompressInit(w.stream, C.int(opts.BlockSize),
+		C.int(opts.Verbosity), C.int(opts.WorkFactor))
+	if ret != C.BZ_OK {
+		C.bz2free(w.stream)
+		w.stream = nil
+		return bzError(ret)
+	}
+	return nil
+}
+
 //!+write
-func (w *writer) Write(data []byte) (int, error) {
+func (w *Writer) Write(data []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
 	if w.stream == nil {
-		panic("closed")
+		return 0, ErrClosed
 	}
 	var total int // uncompressed bytes written
 
 	for len(data) > 0 {
 		inlen, outlen := C.uint(len(data)), C.uint(cap(w.outbuf))
-		C.bz2compress(w.stream, C.BZ_RUN,
+		if inlen > maxChunk {
+			inlen = maxChunk
+		}
+		r := C.bz2compress(w.stream, C.BZ_RUN,
 			(*C.char)(unsafe.Pointer(&data[0])), &inlen,
 			(*C.char)(unsafe.Pointer(&w.outbuf)), &outlen)
+		if r != C.BZ_RUN_OK {
+			w.err = bzError(r)
+			return total, w.err
+		}
 		total += int(inlen)
 		data = data[inlen:]
 		if _, err := w.w.Write(w.outbuf[:outlen]); err != nil {
+			w.err = err
 			return total, err
 		}
 	}
@@ -83,22 +171,61 @@ func (w *writer) Write(data []byte) (int, error) {
 
 //!-write
 
+// Flush writes any pending compressed data to the underlying
+// writer, so that a reader can decompress all the data written so
+// far, as is needed when streaming over a network connection.
+//
+// A bzip2 block does not end on a byte boundary, so Flush ends the
+// current stream and begins another.  NewReader and compress/bzip2
+// decompress the resulting concatenated streams as one, but each
+// flush costs compression ratio and about 14 bytes of overhead.
+func (w *Writer) Flush() error {
+	if w.err != nil {
+		return w.err
+	}
+	if w.stream == nil {
+		return ErrClosed
+	}
+	if w.err = w.finish(); w.err != nil {
+		return w.err
+	}
+	C.BZ2_bzCompressEnd(w.stream)
+	w.err = w.init(w.opts)
+	return w.err
+}
+
 //!+close
 // Close flushes the compressed data and closes the stream.
-// It does not close the underlying io.Writer.
-func (w *writer) Close() error {
+// It does not close the underlying io.Writer.  After an earlier
+// error, it releases the stream and reports that error.
+func (w *Writer) Close() error {
 	if w.stream == nil {
-		panic("closed")
+		if w.err != nil {
+			return w.err
+		}
+		return ErrClosed
 	}
-	defer func() {
-		C.BZ2_bzCompressEnd(w.stream)
-		C.bz2free(w.stream)
-		w.stream = nil
-	}()
+	err := w.err
+	if err == nil {
+		err = w.finish()
+	}
+	C.BZ2_bzCompressEnd(w.stream)
+	C.bz2free(w.stream)
+	w.stream = nil
+	return err
+}
+
+//!-close
+
+// finish compresses any pending data and ends the stream.
+func (w *Writer) finish() error {
 	for {
 		inlen, outlen := C.uint(0), C.uint(cap(w.outbuf))
 		r := C.bz2compress(w.stream, C.BZ_FINISH, nil, &inlen,
 			(*C.char)(unsafe.Pointer(&w.outbuf)), &outlen)
+		if r != C.BZ_FINISH_OK && r != C.BZ_STREAM_END {
+			return bzError(r)
+		}
 		if _, err := w.w.Write(w.outbuf[:outlen]); err != nil {
 			return err
 		}
@@ -107,5 +234,3 @@ func (w *writer) Close() error {
 		}
 	}
 }
-
-//!-close
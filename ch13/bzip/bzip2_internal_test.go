@@ -0,0 +1,63 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package bzip
+
+import (
+	"errors"
+	"testing"
+)
+
+type failWriter struct{}
+
+var errFail = errors.New("write failed")
+
+func (failWriter) Write(p []byte) (int, error) { return 0, errFail }
+
+// TestCloseAfterError checks that Close releases the C stream
+// even after an earlier operation failed.
+func TestCloseAfterError(t *testing.T) {
+	w := NewWriter(failWriter{})
+	w.Write([]byte("hello"))
+	if err := w.Flush(); err != errFail {
+		t.Fatalf("Flush = %v, want %v", err, errFail)
+	}
+	if err := w.Close(); err != errFail {
+		t.Errorf("Close = %v, want %v", err, errFail)
+	}
+	if w.stream != nil {
+		t.Errorf("Close did not release the stream")
+	}
+	if err := w.Close(); err != errFail {
+		t.Errorf("second Close = %v, want %v", err, errFail)
+	}
+}
+
+// failOnceWriter fails its first Write and accepts the rest.
+type failOnceWriter struct{ failed bool }
+
+func (w *failOnceWriter) Write(p []byte) (int, error) {
+	if !w.failed {
+		w.failed = true
+		return 0, errFail
+	}
+	return len(p), nil
+}
+
+// TestWriteError checks that a failed write to the underlying
+// writer is reported by later calls, not lost.
+func TestWriteError(t *testing.T) {
+	w := NewWriter(&failOnceWriter{})
+	if _, err := w.Write([]byte("hello")); err != errFail {
+		t.Fatalf("Write = %v, want %v", err, errFail)
+	}
+	if n, err := w.Write([]byte("world")); n != 0 || err != errFail {
+		t.Errorf("second Write = %d, %v, want 0, %v", n, err, errFail)
+	}
+	if err := w.Close(); err != errFail {
+		t.Errorf("Close = %v, want %v", err, errFail)
+	}
+	if w.stream != nil {
+		t.Errorf("Close did not release the stream")
+	}
+}
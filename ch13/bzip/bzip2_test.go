@@ -7,9 +7,13 @@ import (
 	"bytes"
 	"compress/bzip2" // reader
 	"io"
+	"io/ioutil"
+	"math/rand"
+	"strings"
 	"testing"
+	"testing/iotest"
 
-	"gopl.io/ch13/bzip" // writer
+	"gopl.io/ch13/bzip"
 )
 
 func TestBzip2(t *testing.T) {
@@ -38,3 +42,282 @@ func TestBzip2(t *testing.T) {
 		t.Error("decompression yielded a different message")
 	}
 }
+
+// compress returns the bzip2 compression of data.
+func compress(t *testing.T, data []byte) []byte {
+	var buf bytes.Buffer
+	w := bzip.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// decompress returns the bzip2 decompression of in.
+func decompress(in io.Reader) ([]byte, error) {
+	r := bzip.NewReader(in)
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
+
+// randomText returns n bytes of compressible pseudo-random text.
+func randomText(n int) []byte {
+	rng := rand.New(rand.NewSource(1))
+	words := []string{"hello", "world", "gopher", "bzip2", " ", "\n"}
+	var buf bytes.Buffer
+	for buf.Len() < n {
+		buf.WriteString(words[rng.Intn(len(words))])
+	}
+	return buf.Bytes()[:n]
+}
+
+func TestReader(t *testing.T) {
+	for _, n := range []int{0, 1, 1000, 2000000} {
+		data := randomText(n)
+		compressed := compress(t, data)
+
+		want, err := ioutil.ReadAll(bzip2.NewReader(bytes.NewReader(compressed)))
+		if err != nil {
+			t.Fatalf("compress/bzip2: %v", err)
+		}
+		if !bytes.Equal(want, data) {
+			t.Fatalf("compress/bzip2 decompression of %d bytes differs", n)
+		}
+
+		// Decompress from a reader that returns one byte at a time,
+		// so that the input arrives in the smallest possible pieces.
+		for _, in := range []io.Reader{
+			bytes.NewReader(compressed),
+			iotest.OneByteReader(bytes.NewReader(compressed)),
+		} {
+			got, err := decompress(in)
+			if err != nil {
+				t.Errorf("decompressing %d bytes: %v", n, err)
+			} else if !bytes.Equal(got, data) {
+				t.Errorf("decompression of %d bytes yielded a different message", n)
+			}
+		}
+	}
+}
+
+func TestReaderConcatenated(t *testing.T) {
+	a, b := randomText(5000), []byte("and another stream")
+	in := append(compress(t, a), compress(t, b)...)
+	got, err := decompress(bytes.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := append(a, b...); !bytes.Equal(got, want) {
+		t.Errorf("decompression of concatenated streams = %d bytes, want %d",
+			len(got), len(want))
+	}
+}
+
+func TestReaderErrors(t *testing.T) {
+	compressed := compress(t, randomText(10000))
+
+	corrupt := append([]byte(nil), compressed...)
+	corrupt[len(corrupt)/2] ^= 0xff
+
+	for _, test := range []struct {
+		name string
+		in   []byte
+		want error
+	}{
+		{"not bzip2", []byte("hello, world"), bzip.ErrDataMagic},
+		{"corrupt", corrupt, bzip.ErrData},
+		{"truncated", compressed[:len(compressed)/2], io.ErrUnexpectedEOF},
+		{"trailing garbage", append(compressed, "garbage"...), bzip.ErrDataMagic},
+	} {
+		if _, err := decompress(bytes.NewReader(test.in)); err != test.want {
+			t.Errorf("%s: decompression returned error %v, want %v",
+				test.name, err, test.want)
+		}
+	}
+}
+
+func TestWriterLevel(t *testing.T) {
+	data := randomText(2000000)
+	var sizes []int
+	for _, level := range []int{1, 9} {
+		var buf bytes.Buffer
+		w, err := bzip.NewWriterLevel(&buf, level)
+		if err != nil {
+			t.Fatalf("NewWriterLevel(%d): %v", level, err)
+		}
+		if _, err := w.Write(data); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		sizes = append(sizes, buf.Len())
+		got, err := decompress(&buf)
+		if err != nil || !bytes.Equal(got, data) {
+			t.Errorf("level %d: decompression failed (err=%v)", level, err)
+		}
+	}
+	if sizes[0] <= sizes[1] {
+		t.Errorf("level 1 compressed to %d bytes, level 9 to %d; want level 9 smaller",
+			sizes[0], sizes[1])
+	}
+}
+
+func TestWriterOptionsInvalid(t *testing.T) {
+	for _, level := range []int{-1, 0, 10} {
+		if _, err := bzip.NewWriterLevel(ioutil.Discard, level); err == nil {
+			t.Errorf("NewWriterLevel(%d) succeeded, want error", level)
+		}
+	}
+	for _, opts := range []bzip.WriterOptions{
+		{},
+		{BlockSize: 10},
+		{BlockSize: 9, WorkFactor: -1},
+		{BlockSize: 9, WorkFactor: 251},
+		{BlockSize: 9, Verbosity: 5},
+	} {
+		if _, err := bzip.NewWriterOptions(ioutil.Discard, opts); err == nil {
+			t.Errorf("NewWriterOptions(%+v) succeeded, want error", opts)
+		}
+	}
+}
+
+func TestClosed(t *testing.T) {
+	w := bzip.NewWriter(ioutil.Discard)
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte("hello")); err != bzip.ErrClosed {
+		t.Errorf("Write after Close returned %v, want ErrClosed", err)
+	}
+	if err := w.Flush(); err != bzip.ErrClosed {
+		t.Errorf("Flush after Close returned %v, want ErrClosed", err)
+	}
+	if err := w.Close(); err != bzip.ErrClosed {
+		t.Errorf("second Close returned %v, want ErrClosed", err)
+	}
+
+	r := bzip.NewReader(bytes.NewReader(compress(t, []byte("hello"))))
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Read(make([]byte, 10)); err != bzip.ErrClosed {
+		t.Errorf("Read after Close returned %v, want ErrClosed", err)
+	}
+	if err := r.Close(); err != bzip.ErrClosed {
+		t.Errorf("second Close returned %v, want ErrClosed", err)
+	}
+}
+
+func TestFlush(t *testing.T) {
+	// Send each message through a pipe, as over a network
+	// connection, and check that the reader receives it
+	// before the stream is closed.
+	pr, pw := io.Pipe()
+	w := bzip.NewWriter(pw)
+	r := bzip.NewReader(pr)
+	defer r.Close()
+
+	for _, msg := range []string{"hello", "world", strings.Repeat("gopher", 1000)} {
+		errc := make(chan error, 1)
+		go func() {
+			_, err := io.WriteString(w, msg)
+			if err == nil {
+				err = w.Flush()
+			}
+			errc <- err
+		}()
+		buf := make([]byte, len(msg))
+		if _, err := io.ReadFull(r, buf); err != nil {
+			t.Fatalf("reading flushed message: %v", err)
+		}
+		if string(buf) != msg {
+			t.Errorf("read %q, want %q", buf, msg)
+		}
+		if err := <-errc; err != nil {
+			t.Fatalf("writing message: %v", err)
+		}
+	}
+
+	go func() {
+		w.Close()
+		pw.Close()
+	}()
+	if n, err := io.Copy(ioutil.Discard, r); n != 0 || err != nil {
+		t.Errorf("after last message, read %d bytes, error %v", n, err)
+	}
+}
+
+func TestParallelWriter(t *testing.T) {
+	data := randomText(3500000) // four 900k chunks
+	for _, n := range []int{0, 1, 900000, len(data)} {
+		for _, workers := range []int{1, 4} {
+			var buf bytes.Buffer
+			w := bzip.NewParallelWriter(&buf, workers)
+			// Write in odd-sized pieces that straddle chunks.
+			for in := data[:n]; len(in) > 0; {
+				m := 77777
+				if m > len(in) {
+					m = len(in)
+				}
+				if _, err := w.Write(in[:m]); err != nil {
+					t.Fatal(err)
+				}
+				in = in[m:]
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+			compressed := buf.Bytes()
+
+			for _, r := range []io.Reader{
+				bzip2.NewReader(bytes.NewReader(compressed)),
+				bzip.NewReader(bytes.NewReader(compressed)),
+			} {
+				got, err := ioutil.ReadAll(r)
+				if err != nil {
+					t.Errorf("%d bytes, %d workers: %T: %v", n, workers, r, err)
+				} else if !bytes.Equal(got, data[:n]) {
+					t.Errorf("%d bytes, %d workers: %T: decompression yielded a different message",
+						n, workers, r)
+				}
+			}
+		}
+	}
+}
+
+func TestParallelWriterClosed(t *testing.T) {
+	w := bzip.NewParallelWriter(ioutil.Discard, 2)
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte("hello")); err != bzip.ErrClosed {
+		t.Errorf("Write after Close returned %v, want ErrClosed", err)
+	}
+	if _, err := bzip.NewParallelWriterOptions(ioutil.Discard, 2, bzip.WriterOptions{}); err == nil {
+		t.Errorf("NewParallelWriterOptions with zero options succeeded, want error")
+	}
+}
+
+func BenchmarkWriter(b *testing.B) {
+	data := randomText(4 * 900000)
+	b.SetBytes(int64(len(data)))
+	for i := 0; i < b.N; i++ {
+		w := bzip.NewWriter(ioutil.Discard)
+		w.Write(data)
+		w.Close()
+	}
+}
+
+func BenchmarkParallelWriter(b *testing.B) {
+	data := randomText(4 * 900000)
+	b.SetBytes(int64(len(data)))
+	for i := 0; i < b.N; i++ {
+		w := bzip.NewParallelWriter(ioutil.Discard, 0)
+		w.Write(data)
+		w.Close()
+	}
+}
@@ -0,0 +1,47 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package bzip
+
+/*
+#include <bzlib.h>
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrClosed is returned by operations on a closed Writer or reader.
+var ErrClosed = errors.New("bzip: use of closed stream")
+
+// Errors corresponding to libbzip2 return codes.
+var (
+	ErrSequence  = errors.New("bzip: libbzip2 functions called out of sequence") // BZ_SEQUENCE_ERROR
+	ErrParam     = errors.New("bzip: invalid libbzip2 parameter")                // BZ_PARAM_ERROR
+	ErrMem       = errors.New("bzip: out of memory")                             // BZ_MEM_ERROR
+	ErrData      = errors.New("bzip: corrupt data")                              // BZ_DATA_ERROR
+	ErrDataMagic = errors.New("bzip: not bzip2 data")                            // BZ_DATA_ERROR_MAGIC
+	ErrConfig    = errors.New("bzip: libbzip2 misconfigured for this platform")  // BZ_CONFIG_ERROR
+)
+
+// bzError returns the error for the libbzip2 return code ret.
+func bzError(ret C.int) error {
+	switch ret {
+	case C.BZ_SEQUENCE_ERROR:
+		return ErrSequence
+	case C.BZ_PARAM_ERROR:
+		return ErrParam
+	case C.BZ_MEM_ERROR:
+		return ErrMem
+	case C.BZ_DATA_ERROR:
+		return ErrData
+	case C.BZ_DATA_ERROR_MAGIC:
+		return ErrDataMagic
+	case C.BZ_CONFIG_ERROR:
+		return ErrConfig
+	default:
+		return fmt.Errorf("bzip: libbzip2 error %d", ret)
+	}
+}
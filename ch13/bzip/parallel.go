@@ -0,0 +1,153 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package bzip
+
+import (
+	"bytes"
+	"io"
+	"runtime"
+	"sync"
+)
+
+// A ParallelWriter compresses the data written to it using several
+// goroutines.  It splits its input into chunks of one block each,
+// compresses each chunk concurrently as a separate bzip2 stream,
+// and writes the streams out in order.  NewReader and compress/bzip2
+// decompress the resulting concatenated streams as one.
+type ParallelWriter struct {
+	w         io.Writer // underlying output stream
+	opts      WriterOptions
+	chunkSize int
+	buf       []byte           // pending input, shorter than chunkSize
+	started   bool             // some chunk has been compressed
+	queue     chan chan result // chunks being compressed, in order
+	done      chan struct{}    // closed when the output goroutine exits
+	closed    bool
+
+	mu  sync.Mutex
+	err error // sticky error from compression or output
+}
+
+// A result is the compressed form of a chunk.
+type result struct {
+	data []byte
+	err  error
+}
+
+// NewParallelWriter returns a writer for bzip2-compressed streams
+// that uses up to workers goroutines, or one per CPU if workers is
+// not positive.
+func NewParallelWriter(out io.Writer, workers int) *ParallelWriter {
+	w, _ := NewParallelWriterOptions(out, workers, DefaultWriterOptions)
+	return w
+}
+
+// NewParallelWriterOptions is like NewParallelWriter but uses the
+// specified options.
+func NewParallelWriterOptions(out io.Writer, workers int, opts WriterOptions) (*ParallelWriter, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	w := &ParallelWriter{
+		w:         out,
+		opts:      opts,
+		chunkSize: opts.BlockSize * 100000,
+		queue:     make(chan chan result, workers-1), // plus one awaiting output
+		done:      make(chan struct{}),
+	}
+	go w.output()
+	return w, nil
+}
+
+// output writes the compressed chunks in order.
+func (w *ParallelWriter) output() {
+	defer close(w.done)
+	for ch := range w.queue {
+		res := <-ch
+		if w.error() != nil {
+			continue // drain the queue
+		}
+		if res.err == nil {
+			_, res.err = w.w.Write(res.data)
+		}
+		if res.err != nil {
+			w.setError(res.err)
+		}
+	}
+}
+
+func (w *ParallelWriter) Write(data []byte) (int, error) {
+	if w.closed {
+		return 0, ErrClosed
+	}
+	var total int // uncompressed bytes accepted
+	for len(data) > 0 {
+		if err := w.error(); err != nil {
+			return total, err
+		}
+		n := w.chunkSize - len(w.buf)
+		if n > len(data) {
+			n = len(data)
+		}
+		w.buf = append(w.buf, data[:n]...)
+		total += n
+		data = data[n:]
+		if len(w.buf) == w.chunkSize {
+			w.compress()
+		}
+	}
+	return total, nil
+}
+
+// compress starts compressing the pending input, blocking
+// while all workers are busy.
+func (w *ParallelWriter) compress() {
+	chunk := w.buf
+	w.buf = nil
+	w.started = true
+	ch := make(chan result, 1)
+	w.queue <- ch
+	go func() {
+		var buf bytes.Buffer
+		cw, err := NewWriterOptions(&buf, w.opts)
+		if err == nil {
+			_, err = cw.Write(chunk)
+			if cerr := cw.Close(); err == nil {
+				err = cerr
+			}
+		}
+		ch <- result{buf.Bytes(), err}
+	}()
+}
+
+// Close compresses any pending data, waits for all the compressed
+// data to be written, and closes the writer.
+// It does not close the underlying io.Writer.
+func (w *ParallelWriter) Close() error {
+	if w.closed {
+		return ErrClosed
+	}
+	w.closed = true
+	if len(w.buf) > 0 || !w.started {
+		w.compress() // an empty input still needs one stream
+	}
+	close(w.queue)
+	<-w.done
+	return w.error()
+}
+
+func (w *ParallelWriter) error() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.err
+}
+
+func (w *ParallelWriter) setError(err error) {
+	w.mu.Lock()
+	w.err = err
+	w.mu.Unlock()
+}
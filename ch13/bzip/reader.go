@@ -0,0 +1,143 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package bzip
+
+/*
+#include <bzlib.h>
+bz_stream* bz2alloc();
+int bz2decompress(bz_stream *s,
+                  char *in, unsigned *inlen, char *out, unsigned *outlen);
+void bz2free(bz_stream* s);
+*/
+import "C"
+
+import (
+	"io"
+	"unsafe"
+)
+
+// maxChunk is the largest buffer passed to libbzip2 in one call.
+const maxChunk = 1 << 30
+
+type reader struct {
+	r      io.Reader // underlying input stream
+	stream *C.bz_stream
+	inbuf  [64 * 1024]byte
+	in     []byte // unconsumed portion of inbuf
+	eof    bool   // underlying stream is exhausted
+	ended  bool   // current bzip2 stream has ended
+	err    error  // sticky error
+}
+
+// NewReader returns a reader that decompresses bzip2-compressed
+// data from in.  A sequence of concatenated streams, such as that
+// produced by compressing several files separately and joining
+// the results, is decompressed as a single stream.
+func NewReader(in io.Reader) io.ReadCloser {
+	r := &reader{r: in, stream: C.bz2alloc()}
+	r.err = r.init()
+	return r
+}
+
+// init initializes the decompressor for a new stream.
+func (r *reader) init() error {
+	const verbosity = 0
+	const small = 0 // use the faster, larger-memory algorithm
+	if ret := C.BZ2_bzDecompressInit(r.stream, verbosity, small); ret != C.BZ_OK {
+		return bzError(ret)
+	}
+	return nil
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	if r.stream == nil {
+		return 0, ErrClosed
+	}
+	if r.err != nil {
+		return 0, r.err
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if len(p) > maxChunk {
+		p = p[:maxChunk] // inlen and outlen are C.uint
+	}
+	for {
+		if r.ended {
+			// Look for another stream only now, so that a reader
+			// at a stream boundary need not wait for more input.
+			if r.err = r.next(); r.err != nil {
+				return 0, r.err
+			}
+			r.ended = false
+		}
+		if len(r.in) == 0 && !r.eof {
+			if err := r.fill(); err != nil {
+				r.err = err
+				return 0, err
+			}
+		}
+
+		var inptr *C.char
+		if len(r.in) > 0 {
+			inptr = (*C.char)(unsafe.Pointer(&r.in[0]))
+		}
+		inlen, outlen := C.uint(len(r.in)), C.uint(len(p))
+		ret := C.bz2decompress(r.stream, inptr, &inlen,
+			(*C.char)(unsafe.Pointer(&p[0])), &outlen)
+		r.in = r.in[inlen:]
+
+		switch ret {
+		case C.BZ_OK:
+			if outlen == 0 && inlen == 0 && r.eof {
+				r.err = io.ErrUnexpectedEOF // truncated stream
+			}
+		case C.BZ_STREAM_END:
+			r.ended = true
+		default:
+			r.err = bzError(ret)
+		}
+		if outlen > 0 || r.err != nil {
+			return int(outlen), r.err
+		}
+	}
+}
+
+// fill reads more compressed data into the input buffer.
+func (r *reader) fill() error {
+	n, err := r.r.Read(r.inbuf[:])
+	r.in = r.inbuf[:n]
+	if err == io.EOF {
+		r.eof = true
+		err = nil
+	}
+	return err
+}
+
+// next prepares to decompress the stream following the one just
+// ended, if any.  It returns io.EOF at the end of the input.
+func (r *reader) next() error {
+	if len(r.in) == 0 && !r.eof {
+		if err := r.fill(); err != nil {
+			return err
+		}
+	}
+	if len(r.in) == 0 && r.eof {
+		return io.EOF
+	}
+	C.BZ2_bzDecompressEnd(r.stream)
+	return r.init()
+}
+
+// Close releases the resources of the decompressor.
+// It does not close the underlying io.Reader.
+func (r *reader) Close() error {
+	if r.stream == nil {
+		return ErrClosed
+	}
+	C.BZ2_bzDecompressEnd(r.stream)
+	C.bz2free(r.stream)
+	r.stream = nil
+	return nil
+}
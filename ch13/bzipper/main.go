@@ -3,20 +3,125 @@
 
 // See page 365.
 
-//!+
-
-// Bzipper reads input, bzip2-compresses it, and writes it out.
+// Bzipper compresses or decompresses files using bzip2.
+//
+// Usage:
+//
+//	bzipper [-d | -t] [-c] [-k] [-f] [-1 ... -9] [-j n] [file ...]
+//
+// With no file arguments, bzipper filters its standard input to its
+// standard output.  Otherwise it replaces each file by a compressed
+// version with a .bz2 suffix, or, with -d, by its decompressed
+// version without the suffix.  Files are processed concurrently,
+// and each output file is written atomically.
 package main
 
 import (
+	"bufio"
+	"flag"
+	"fmt"
 	"io"
 	"log"
 	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
 
 	"gopl.io/ch13/bzip"
 )
 
+var (
+	decompress = flag.Bool("d", false, "decompress")
+	test       = flag.Bool("t", false, "test the integrity of compressed files")
+	stdout     = flag.Bool("c", false, "write to standard output; implies -k")
+	keep       = flag.Bool("k", false, "keep (don't delete) input files")
+	force      = flag.Bool("f", false, "overwrite existing output files")
+	jobs       = flag.Int("j", 1, "compress using `n` goroutines (0 means one per CPU)")
+	level      = 9
+)
+
+func init() {
+	for n := 1; n <= 9; n++ {
+		flag.Var(levelFlag{&level, n}, strconv.Itoa(n),
+			fmt.Sprintf("compress using %dk blocks", n*100))
+	}
+}
+
+// A levelFlag is a boolean flag that sets the compression level.
+// If several level flags are given, the last one wins.
+type levelFlag struct {
+	level *int
+	n     int
+}
+
+func (f levelFlag) IsBoolFlag() bool { return true }
+func (f levelFlag) String() string   { return "" }
+func (f levelFlag) Set(s string) error {
+	b, err := strconv.ParseBool(s)
+	if b {
+		*f.level = f.n
+	}
+	return err
+}
+
 func main() {
+	flag.Parse()
+	if flag.NArg() == 0 && !*decompress && !*test && *jobs == 1 && level == 9 {
+		bzip2Stdin()
+		return
+	}
+
+	log.SetFlags(0)
+	log.SetPrefix("bzipper: ")
+	if *decompress && *test {
+		log.Fatal("-d and -t are mutually exclusive")
+	}
+
+	if flag.NArg() == 0 {
+		if _, _, err := filter(os.Stdout, os.Stdin); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	// With -c, files are processed in turn, since their outputs
+	// are concatenated.  Otherwise, one file per CPU at a time.
+	workers := runtime.NumCPU()
+	if *stdout {
+		workers = 1
+	}
+	sema := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	failed := false
+	for _, name := range flag.Args() {
+		wg.Add(1)
+		sema <- struct{}{} // acquire token
+		go func(name string) {
+			defer wg.Done()
+			defer func() { <-sema }() // release token
+			if err := process(name); err != nil {
+				log.Print(err)
+				mu.Lock()
+				failed = true
+				mu.Unlock()
+			}
+		}(name)
+	}
+	wg.Wait()
+	if failed {
+		os.Exit(1)
+	}
+}
+
+//!+
+
+// bzip2Stdin is the original program, which reads input,
+// bzip2-compresses it, and writes it out.  It is the default
+// behavior when there are no arguments.
+func bzip2Stdin() {
 	w := bzip.NewWriter(os.Stdout)
 	if _, err := io.Copy(w, os.Stdin); err != nil {
 		log.Fatalf("bzipper: %v\n", err)
@@ -27,3 +132,173 @@ func main() {
 }
 
 //!-
+
+// filter compresses, decompresses, or tests the data from in, writing
+// the result to out, and returns the compressed and uncompressed sizes.
+func filter(out io.Writer, in io.Reader) (compressed, uncompressed int64, err error) {
+	if *decompress || *test {
+		cin := &countingReader{r: in}
+		r := bzip.NewReader(bufio.NewReader(cin))
+		defer r.Close()
+		if *test {
+			out = io.Discard
+		}
+		uncompressed, err = io.Copy(out, r)
+		return cin.n, uncompressed, err
+	}
+
+	cout := &countingWriter{w: out}
+	var w io.WriteCloser
+	if *jobs != 1 {
+		w, err = bzip.NewParallelWriterOptions(cout, *jobs, options())
+	} else {
+		w, err = bzip.NewWriterLevel(cout, level)
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+	uncompressed, err = io.Copy(w, in)
+	if cerr := w.Close(); err == nil {
+		err = cerr
+	}
+	return cout.n, uncompressed, err
+}
+
+func options() bzip.WriterOptions {
+	opts := bzip.DefaultWriterOptions
+	opts.BlockSize = level
+	return opts
+}
+
+// process compresses, decompresses, or tests the named file.
+func process(name string) error {
+	in, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("%s: not a regular file", name)
+	}
+
+	var compressed, uncompressed int64
+	switch {
+	case *test:
+		compressed, uncompressed, err = filter(nil, in)
+		if err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+		fmt.Fprintf(os.Stderr, "%s: ok\n", name)
+		return nil
+
+	case *stdout:
+		compressed, uncompressed, err = filter(os.Stdout, in)
+
+	default:
+		outname := name + ".bz2"
+		if !*decompress && strings.HasSuffix(name, ".bz2") {
+			return fmt.Errorf("%s: already has .bz2 suffix", name)
+		}
+		if *decompress {
+			outname = strings.TrimSuffix(name, ".bz2")
+			if outname == name {
+				outname = name + ".out"
+				log.Printf("%s: can't guess original name, using %s", name, outname)
+			}
+		}
+		err = writeFile(outname, info.Mode().Perm(), func(out io.Writer) error {
+			compressed, uncompressed, err = filter(out, in)
+			return err
+		})
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+	report(name, compressed, uncompressed)
+
+	if !*keep && !*stdout {
+		in.Close()
+		if err := os.Remove(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeFile atomically creates or replaces the named file with the
+// output of write, by writing to a temporary file in the same
+// directory and renaming it.
+func writeFile(name string, perm os.FileMode, write func(io.Writer) error) error {
+	if !*force {
+		if _, err := os.Lstat(name); err == nil {
+			return fmt.Errorf("output file %s already exists", name)
+		}
+	}
+	f, err := os.CreateTemp(filepath.Dir(name), filepath.Base(name)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name()) // no-op after a successful rename
+
+	bw := bufio.NewWriter(f)
+	err = write(bw)
+	if err == nil {
+		err = bw.Flush()
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(f.Name(), perm)
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), name)
+}
+
+// report prints the compression ratio of the named file.
+func report(name string, compressed, uncompressed int64) {
+	if compressed == 0 || uncompressed == 0 {
+		fmt.Fprintf(os.Stderr, "%s: no data compressed.\n", name)
+		return
+	}
+	in, out := uncompressed, compressed
+	if *decompress {
+		in, out = out, in
+	}
+	ratio := float64(uncompressed) / float64(compressed)
+	fmt.Fprintf(os.Stderr, "%s: %6.3f:1, %6.3f bits/byte, %5.2f%% saved, %d in, %d out.\n",
+		name, ratio, 8/ratio, 100*(1-1/ratio), in, out)
+}
+
+// A countingWriter counts the bytes written through it.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// A countingReader counts the bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -7,17 +7,20 @@
 package equal
 
 import (
+	"fmt"
+	"math"
 	"reflect"
 	"unsafe"
 )
 
 //!+
-func equal(x, y reflect.Value, seen map[comparison]bool) bool {
+func (c *comparer) equal(x, y reflect.Value) bool {
 	if !x.IsValid() || !y.IsValid() {
-		return x.IsValid() == y.IsValid()
+		return x.IsValid() == y.IsValid() || c.mismatch(x, y)
 	}
 	if x.Type() != y.Type() {
-		return false
+		return c.mismatchf("%s (%s) != %s (%s)",
+			formatValue(x), x.Type(), formatValue(y), y.Type())
 	}
 
 	// ...cycle check omitted (shown later)...
@@ -31,51 +34,69 @@ func equal(x, y reflect.Value, seen map[comparison]bool) bool {
 		if xptr == yptr {
 			return true // identical references
 		}
-		c := comparison{xptr, yptr, x.Type()}
-		if seen[c] {
+		k := comparison{xptr, yptr, x.Type()}
+		if c.seen[k] {
 			return true // already seen
 		}
-		seen[c] = true
+		c.seen[k] = true
+		if c.UnorderedSlices {
+			c.log = append(c.log, k)
+		}
 	}
 	//!-cyclecheck
 	//!+
 	switch x.Kind() {
 	case reflect.Bool:
-		return x.Bool() == y.Bool()
+		return x.Bool() == y.Bool() || c.mismatch(x, y)
 
 	case reflect.String:
-		return x.String() == y.String()
+		return x.String() == y.String() || c.mismatch(x, y)
 
 	// ...numeric cases omitted for brevity...
 
 	//!-
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
 		reflect.Int64:
-		return x.Int() == y.Int()
+		return x.Int() == y.Int() || c.mismatch(x, y)
 
 	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
 		reflect.Uint64, reflect.Uintptr:
-		return x.Uint() == y.Uint()
+		return x.Uint() == y.Uint() || c.mismatch(x, y)
 
 	case reflect.Float32, reflect.Float64:
-		return x.Float() == y.Float()
+		return c.floatEqual(x.Float(), y.Float()) || c.mismatch(x, y)
 
 	case reflect.Complex64, reflect.Complex128:
-		return x.Complex() == y.Complex()
+		xc, yc := x.Complex(), y.Complex()
+		return c.floatEqual(real(xc), real(yc)) &&
+			c.floatEqual(imag(xc), imag(yc)) || c.mismatch(x, y)
 	//!+
-	case reflect.Chan, reflect.UnsafePointer, reflect.Func:
-		return x.Pointer() == y.Pointer()
+	case reflect.Func:
+		if c.FuncsByNilness {
+			return x.IsNil() == y.IsNil() || c.mismatch(x, y)
+		}
+		return x.Pointer() == y.Pointer() || c.mismatch(x, y)
+
+	case reflect.Chan, reflect.UnsafePointer:
+		return x.Pointer() == y.Pointer() || c.mismatch(x, y)
 
 	case reflect.Ptr, reflect.Interface:
-		return equal(x.Elem(), y.Elem(), seen)
+		return c.equal(x.Elem(), y.Elem())
 
 	case reflect.Array, reflect.Slice:
+		if x.Kind() == reflect.Slice && !c.NilEqualsEmpty &&
+			x.IsNil() != y.IsNil() {
+			return c.mismatch(x, y)
+		}
 		if x.Len() != y.Len() {
-			return false
+			return c.mismatchf("len %d != len %d", x.Len(), y.Len())
+		}
+		if x.Kind() == reflect.Slice && c.UnorderedSlices {
+			return c.unorderedEqual(x, y)
 		}
 		for i := 0; i < x.Len(); i++ {
-			if !equal(x.Index(i), y.Index(i), seen) {
-				return false
+			if !c.equal(x.Index(i), y.Index(i)) {
+				return c.prefix(fmt.Sprintf("[%d]", i))
 			}
 		}
 		return true
@@ -84,19 +105,30 @@ func equal(x, y reflect.Value, seen map[comparison]bool) bool {
 	//!-
 	case reflect.Struct:
 		for i, n := 0, x.NumField(); i < n; i++ {
-			if !equal(x.Field(i), y.Field(i), seen) {
-				return false
+			if c.ignore[x.Type().Field(i).Name] {
+				continue
+			}
+			if !c.equal(x.Field(i), y.Field(i)) {
+				return c.prefix("." + x.Type().Field(i).Name)
 			}
 		}
 		return true
 
 	case reflect.Map:
+		if !c.NilEqualsEmpty && x.IsNil() != y.IsNil() {
+			return c.mismatch(x, y)
+		}
 		if x.Len() != y.Len() {
-			return false
+			return c.mismatchf("len %d != len %d", x.Len(), y.Len())
 		}
 		for _, k := range x.MapKeys() {
-			if !equal(x.MapIndex(k), y.MapIndex(k), seen) {
-				return false
+			yv := y.MapIndex(k)
+			if !yv.IsValid() {
+				c.mismatchf("%s != <missing>", formatValue(x.MapIndex(k)))
+				return c.prefix("[" + formatValue(k) + "]")
+			}
+			if !c.equal(x.MapIndex(k), yv) {
+				return c.prefix("[" + formatValue(k) + "]")
 			}
 		}
 		return true
@@ -107,6 +139,81 @@ func equal(x, y reflect.Value, seen map[comparison]bool) bool {
 
 //!-
 
+// floatEqual reports whether x and y are equal within the
+// configured tolerance.
+func (c *comparer) floatEqual(x, y float64) bool {
+	if math.IsNaN(x) || math.IsNaN(y) {
+		return c.NaNEqual && math.IsNaN(x) && math.IsNaN(y)
+	}
+	return x == y || math.Abs(x-y) <= c.FloatTolerance
+}
+
+// unorderedEqual reports whether the slices x and y, of equal
+// length, are permutations of each other.  Each element of x is
+// matched against the first deeply equal unmatched element of y.
+func (c *comparer) unorderedEqual(x, y reflect.Value) bool {
+	matched := make([]bool, y.Len())
+outer:
+	for i := 0; i < x.Len(); i++ {
+		for j := 0; j < y.Len(); j++ {
+			if !matched[j] && c.try(x.Index(i), y.Index(j)) {
+				matched[j] = true
+				continue outer
+			}
+		}
+		c.mismatchf("no match for %s", formatValue(x.Index(i)))
+		return c.prefix(fmt.Sprintf("[%d]", i))
+	}
+	return true
+}
+
+// try is like equal, but if x and y are unequal it forgets the
+// comparisons and the difference recorded along the way, since a
+// failed trial match must not leave pairs marked as already seen.
+func (c *comparer) try(x, y reflect.Value) bool {
+	mark := len(c.log)
+	if c.equal(x, y) {
+		return true
+	}
+	for _, k := range c.log[mark:] {
+		delete(c.seen, k)
+	}
+	c.log = c.log[:mark]
+	c.path, c.diff = "", ""
+	return false
+}
+
+// mismatch records x != y as the difference found, if the
+// comparer is explaining, and returns false.
+func (c *comparer) mismatch(x, y reflect.Value) bool {
+	return c.mismatchf("%s != %s", formatValue(x), formatValue(y))
+}
+
+// mismatchf is like mismatch, but formats the difference itself.
+func (c *comparer) mismatchf(format string, args ...interface{}) bool {
+	if c.explain {
+		c.diff = fmt.Sprintf(format, args...)
+	}
+	return false
+}
+
+// prefix prepends segment to the path of the difference found,
+// if the comparer is explaining, and returns false.
+func (c *comparer) prefix(segment string) bool {
+	if c.explain {
+		c.path = segment + c.path
+	}
+	return false
+}
+
+// formatValue formats v for an explanation.
+func formatValue(v reflect.Value) string {
+	if !v.IsValid() {
+		return "nil"
+	}
+	return fmt.Sprintf("%#v", v)
+}
+
 //!+comparison
 // Equal reports whether x and y are deeply equal.
 //!-comparison
@@ -115,8 +222,7 @@ func equal(x, y reflect.Value, seen map[comparison]bool) bool {
 // (This matters for keys containing pointers or interfaces.)
 //!+comparison
 func Equal(x, y interface{}) bool {
-	seen := make(map[comparison]bool)
-	return equal(reflect.ValueOf(x), reflect.ValueOf(y), seen)
+	return EqualWith(x, y, Options{NilEqualsEmpty: true})
 }
 
 type comparison struct {
@@ -125,3 +231,87 @@ type comparison struct {
 }
 
 //!-comparison
+
+// Options configures the equivalence relation used by EqualWith.
+// The zero Options is stricter than Equal, which is EqualWith
+// with NilEqualsEmpty set.
+type Options struct {
+	// FloatTolerance is the largest absolute difference between
+	// two floating-point values, or between the real or imaginary
+	// parts of two complex values, that are considered equal.
+	FloatTolerance float64
+
+	// NaNEqual causes NaN to equal NaN.
+	NaNEqual bool
+
+	// NilEqualsEmpty causes a nil slice or map to equal
+	// an empty non-nil one.
+	NilEqualsEmpty bool
+
+	// IgnoreFields lists the names of struct fields, in structs
+	// of any type and at any depth, that are not compared.
+	IgnoreFields []string
+
+	// UnorderedSlices causes slices to be compared as multisets,
+	// ignoring the order of their elements.  It takes time
+	// quadratic in the length of the slices.
+	UnorderedSlices bool
+
+	// FuncsByNilness causes functions to be compared by nilness:
+	// any two non-nil functions of the same type are equal.
+	// Otherwise functions are equal if both are nil or both have
+	// the same code pointer, as reported by reflect.Value.Pointer,
+	// so a function equals itself, and closures created by the same
+	// function literal may be equal even if their variables differ.
+	FuncsByNilness bool
+}
+
+// EqualWith reports whether x and y are deeply equal
+// under the relation configured by opts.
+func EqualWith(x, y interface{}, opts Options) bool {
+	c := newComparer(opts)
+	return c.equal(reflect.ValueOf(x), reflect.ValueOf(y))
+}
+
+// Explain is like Equal, but also describes the first difference
+// between unequal values, as the access path to it followed by the
+// two differing values, for example:
+//
+//	.Items[3].User.Login: "a" != "b"
+//
+// The path is omitted if x and y themselves differ.
+// Explain returns true and the empty string if x and y are equal.
+func Explain(x, y interface{}) (bool, string) {
+	c := newComparer(Options{NilEqualsEmpty: true})
+	c.explain = true
+	if c.equal(reflect.ValueOf(x), reflect.ValueOf(y)) {
+		return true, ""
+	}
+	if c.path == "" {
+		return false, c.diff // top-level difference
+	}
+	return false, c.path + ": " + c.diff
+}
+
+// A comparer holds the state of one deep comparison.
+type comparer struct {
+	Options
+	ignore map[string]bool     // set of IgnoreFields
+	seen   map[comparison]bool // pairs already (being) compared
+	log    []comparison        // keys of seen, if UnorderedSlices
+
+	explain    bool   // record the first difference
+	path, diff string // access path to, and description of, the difference
+}
+
+func newComparer(opts Options) *comparer {
+	c := &comparer{
+		Options: opts,
+		ignore:  make(map[string]bool),
+		seen:    make(map[comparison]bool),
+	}
+	for _, name := range opts.IgnoreFields {
+		c.ignore[name] = true
+	}
+	return c
+}
@@ -6,6 +6,8 @@ package equal
 import (
 	"bytes"
 	"fmt"
+	"math"
+	"reflect"
 	"testing"
 )
 
@@ -131,3 +133,134 @@ func Example_equalCycle() {
 	// false
 	// false
 }
+
+func TestEqualWith(t *testing.T) {
+	type item struct {
+		Name      string
+		CreatedAt int64
+	}
+	nan := math.NaN()
+	tenth, fifth := 0.1, 0.2
+
+	for _, test := range []struct {
+		x, y interface{}
+		opts Options
+		want bool
+	}{
+		// zero Options
+		{[]int{}, []int(nil), Options{}, false},
+		{map[int]int{}, map[int]int(nil), Options{}, false},
+		{[]int(nil), []int(nil), Options{}, true},
+		{tenth + fifth, 0.3, Options{}, false},
+		{nan, nan, Options{}, false},
+		{func() {}, func() {}, Options{}, false},
+		// NilEqualsEmpty
+		{[]int{}, []int(nil), Options{NilEqualsEmpty: true}, true},
+		{map[int]int{}, map[int]int(nil), Options{NilEqualsEmpty: true}, true},
+		// FloatTolerance
+		{tenth + fifth, 0.3, Options{FloatTolerance: 1e-9}, true},
+		{1.0, 1.1, Options{FloatTolerance: 1e-9}, false},
+		{float32(1), float32(1.00001), Options{FloatTolerance: 1e-4}, true},
+		{complex(1, 2), complex(1, 2.0000001), Options{FloatTolerance: 1e-6}, true},
+		{complex(1, 2), complex(1, 2.1), Options{FloatTolerance: 1e-6}, false},
+		// NaNEqual
+		{nan, nan, Options{NaNEqual: true}, true},
+		{nan, 1.0, Options{NaNEqual: true}, false},
+		{[]float64{1, nan}, []float64{1, nan}, Options{NaNEqual: true}, true},
+		// IgnoreFields
+		{item{"a", 1}, item{"a", 2}, Options{}, false},
+		{item{"a", 1}, item{"a", 2}, Options{IgnoreFields: []string{"CreatedAt"}}, true},
+		{
+			map[string]*item{"x": {"a", 1}},
+			map[string]*item{"x": {"b", 1}},
+			Options{IgnoreFields: []string{"CreatedAt"}},
+			false,
+		},
+		// UnorderedSlices
+		{[]int{1, 2, 3}, []int{3, 1, 2}, Options{}, false},
+		{[]int{1, 2, 3}, []int{3, 1, 2}, Options{UnorderedSlices: true}, true},
+		{[]int{1, 1, 2}, []int{1, 2, 2}, Options{UnorderedSlices: true}, false},
+		{[...]int{1, 2}, [...]int{2, 1}, Options{UnorderedSlices: true}, false},
+		{
+			[][]string{{"a", "b"}, {"c"}},
+			[][]string{{"c"}, {"b", "a"}},
+			Options{UnorderedSlices: true},
+			true,
+		},
+		{
+			[]*item{{"a", 1}, {"b", 2}},
+			[]*item{{"b", 3}, {"a", 4}},
+			Options{UnorderedSlices: true, IgnoreFields: []string{"CreatedAt"}},
+			true,
+		},
+		// FuncsByNilness
+		{func() {}, func() {}, Options{FuncsByNilness: true}, true},
+		{(func())(nil), func() {}, Options{FuncsByNilness: true}, false},
+	} {
+		if got := EqualWith(test.x, test.y, test.opts); got != test.want {
+			t.Errorf("EqualWith(%v, %v, %+v) = %t",
+				test.x, test.y, test.opts, got)
+		}
+	}
+}
+
+func TestExplain(t *testing.T) {
+	type user struct{ Login string }
+	type item struct {
+		User  *user
+		Count int
+	}
+	type order struct {
+		Items []item
+		Tags  map[string][]int
+	}
+	mk := func() order {
+		return order{
+			Items: []item{{&user{"a"}, 1}, {&user{"b"}, 2}},
+			Tags:  map[string][]int{"x": {1, 2}},
+		}
+	}
+	a := mk()
+
+	b := mk()
+	b.Items[1].User.Login = "c"
+
+	c := mk()
+	c.Tags["x"] = append(c.Tags["x"], 3)
+
+	d := mk()
+	delete(d.Tags, "x")
+	d.Tags["y"] = nil
+
+	for _, test := range []struct {
+		x, y interface{}
+		want string
+	}{
+		{a, mk(), ""},
+		{a, b, `.Items[1].User.Login: "b" != "c"`},
+		{a, c, `.Tags["x"]: len 2 != len 3`},
+		{a, d, `.Tags["x"]: []int{1, 2} != <missing>`},
+		{1, 1.0, "1 (int) != 1 (float64)"},
+		{nil, 1, "nil != 1"},
+		{[]int{1, 2}, []int{1, 3}, "[1]: 2 != 3"},
+	} {
+		ok, got := Explain(test.x, test.y)
+		if ok != (test.want == "") || got != test.want {
+			t.Errorf("Explain(%v, %v) = %t, %q, want %q",
+				test.x, test.y, ok, got, test.want)
+		}
+	}
+}
+
+func TestExplainUnordered(t *testing.T) {
+	c := newComparer(Options{UnorderedSlices: true})
+	c.explain = true
+	x := [][]int{{1}, {2}, {3}}
+	y := [][]int{{3}, {1}, {4}}
+	if c.equal(reflect.ValueOf(x), reflect.ValueOf(y)) {
+		t.Fatalf("equal(%v, %v) = true", x, y)
+	}
+	if got, want := c.path+": "+c.diff, "[1]: no match for []int{2}"; got != want {
+		t.Errorf("difference = %q, want %q", got, want)
+	}
+}
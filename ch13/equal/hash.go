@@ -0,0 +1,159 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package equal
+
+import (
+	"encoding/binary"
+	"hash"
+	"hash/fnv"
+	"math"
+	"reflect"
+	"unsafe"
+)
+
+// maxHashDepth is the number of pointer, slice and map references
+// that Hash follows from its argument.
+const maxHashDepth = 20
+
+// Hash returns a hash of x that is consistent with Equal:
+// if Equal(x, y), then Hash(x) == Hash(y).
+//
+// Hash is deterministic and independent of map iteration order.
+// Because Equal deems two cyclic values equal if their cycles
+// unroll to the same infinite value, whatever their lengths,
+// Hash considers only the part of x reachable through at most
+// 20 (maxHashDepth) references.  Deeper differences cause collisions.
+func Hash(x interface{}) uint64 {
+	h := hasher{seen: make(map[visit]uint64)}
+	return h.hash(reflect.ValueOf(x), maxHashDepth)
+}
+
+// A hasher memoizes the hashes of the addressable values it has
+// visited, so that shared and cyclic structures are hashed in time
+// proportional to their size times maxHashDepth.
+type hasher struct {
+	seen map[visit]uint64
+}
+
+type visit struct {
+	ptr   unsafe.Pointer
+	t     reflect.Type
+	depth int
+}
+
+func (h *hasher) hash(v reflect.Value, depth int) uint64 {
+	if !v.IsValid() {
+		return 0
+	}
+
+	var k visit
+	if v.CanAddr() {
+		k = visit{unsafe.Pointer(v.UnsafeAddr()), v.Type(), depth}
+		if sum, ok := h.seen[k]; ok {
+			return sum
+		}
+	}
+
+	d := digest{fnv.New64a()}
+	d.writeString(v.Type().String())
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			d.writeUint64(1)
+		} else {
+			d.writeUint64(0)
+		}
+
+	case reflect.String:
+		d.writeUint64(uint64(v.Len()))
+		d.writeString(v.String())
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Int64:
+		d.writeUint64(uint64(v.Int()))
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64, reflect.Uintptr:
+		d.writeUint64(v.Uint())
+
+	case reflect.Float32, reflect.Float64:
+		d.writeFloat(v.Float())
+
+	case reflect.Complex64, reflect.Complex128:
+		d.writeFloat(real(v.Complex()))
+		d.writeFloat(imag(v.Complex()))
+
+	case reflect.Chan, reflect.UnsafePointer, reflect.Func:
+		d.writeUint64(uint64(v.Pointer()))
+
+	case reflect.Ptr:
+		if depth > 0 {
+			d.writeUint64(h.hash(v.Elem(), depth-1))
+		}
+
+	case reflect.Interface:
+		d.writeUint64(h.hash(v.Elem(), depth))
+
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			d.writeUint64(h.hash(v.Index(i), depth))
+		}
+
+	case reflect.Slice:
+		// A nil slice equals an empty one, so hash only the elements.
+		d.writeUint64(uint64(v.Len()))
+		if depth > 0 {
+			for i := 0; i < v.Len(); i++ {
+				d.writeUint64(h.hash(v.Index(i), depth-1))
+			}
+		}
+
+	case reflect.Struct:
+		for i, n := 0, v.NumField(); i < n; i++ {
+			d.writeUint64(h.hash(v.Field(i), depth))
+		}
+
+	case reflect.Map:
+		d.writeUint64(uint64(v.Len()))
+		if depth > 0 {
+			// Sum the entry hashes, which is independent of order.
+			var sum uint64
+			for _, key := range v.MapKeys() {
+				e := digest{fnv.New64a()}
+				e.writeUint64(h.hash(key, depth-1))
+				e.writeUint64(h.hash(v.MapIndex(key), depth-1))
+				sum += e.Sum64()
+			}
+			d.writeUint64(sum)
+		}
+	}
+
+	sum := d.Sum64()
+	if v.CanAddr() {
+		h.seen[k] = sum
+	}
+	return sum
+}
+
+// A digest is a 64-bit hash with helpers for writing fixed-size values.
+type digest struct {
+	hash.Hash64
+}
+
+func (d digest) writeString(s string) {
+	d.Write([]byte(s))
+}
+
+func (d digest) writeUint64(x uint64) {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], x)
+	d.Write(buf[:])
+}
+
+func (d digest) writeFloat(f float64) {
+	if f == 0 {
+		f = 0 // -0 == +0
+	}
+	d.writeUint64(math.Float64bits(f))
+}
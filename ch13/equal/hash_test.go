@@ -0,0 +1,109 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package equal
+
+import (
+	"math"
+	"testing"
+)
+
+func TestHash(t *testing.T) {
+	one, oneAgain, two := 1, 1, 2
+
+	type CyclePtr *CyclePtr
+	var cyclePtr1, cyclePtr2 CyclePtr
+	cyclePtr1 = &cyclePtr1
+	cyclePtr2 = &cyclePtr2
+
+	// Circular linked lists a -> b -> a and c -> c, all with
+	// the same value, are deeply equal.
+	type link struct {
+		value string
+		tail  *link
+	}
+	a, b, c := &link{value: "x"}, &link{value: "x"}, &link{value: "x"}
+	a.tail, b.tail, c.tail = b, a, c
+
+	bigMap1, bigMap2 := make(map[int]string), make(map[int]string)
+	for i := 0; i < 100; i++ {
+		bigMap1[i] = "v"
+		bigMap2[99-i] = "v"
+	}
+
+	negZero := math.Copysign(0, -1)
+
+	for _, test := range []struct {
+		x, y interface{}
+	}{
+		{1, 1},
+		{"foo", "foo"},
+		{[]string{}, []string(nil)},
+		{map[string][]int{}, map[string][]int(nil)},
+		{map[string][]int{"a": {1}, "b": {2}}, map[string][]int{"b": {2}, "a": {1}}},
+		{bigMap1, bigMap2},
+		{&one, &oneAgain},
+		{cyclePtr1, cyclePtr2},
+		{a, c},
+		{0.0, negZero},
+		{[...]interface{}{1, "a"}, [...]interface{}{1, "a"}},
+	} {
+		if !Equal(test.x, test.y) {
+			t.Errorf("Equal(%v, %v) = false", test.x, test.y)
+			continue
+		}
+		if hx, hy := Hash(test.x), Hash(test.y); hx != hy {
+			t.Errorf("Hash(%v) = %#x, Hash(%v) = %#x, want equal",
+				test.x, hx, test.y, hy)
+		}
+	}
+
+	type mystring string
+	for _, test := range []struct {
+		x, y interface{}
+	}{
+		{1, 2},
+		{1, 1.0},
+		{"foo", mystring("foo")},
+		{[]string{"a", "b"}, []string{"ab"}},
+		{[]int{1, 2}, []int{2, 1}},
+		{map[int]int{1: 2}, map[int]int{2: 1}},
+		{&one, &two},
+		{nil, 0},
+	} {
+		if Hash(test.x) == Hash(test.y) {
+			t.Errorf("Hash(%v) == Hash(%v), want (probably) different", test.x, test.y)
+		}
+	}
+}
+
+func TestHashDedupe(t *testing.T) {
+	type node struct {
+		Name     string
+		Children []*node
+	}
+	mk := func(name string) *node {
+		n := &node{Name: name}
+		n.Children = []*node{{Name: "leaf"}, n} // a cycle
+		return n
+	}
+	buckets := make(map[uint64][]interface{})
+	var unique int
+	for _, x := range []*node{mk("a"), mk("b"), mk("a"), mk("a"), mk("b")} {
+		h := Hash(x)
+		dup := false
+		for _, y := range buckets[h] {
+			if Equal(x, y) {
+				dup = true
+				break
+			}
+		}
+		if !dup {
+			buckets[h] = append(buckets[h], x)
+			unique++
+		}
+	}
+	if unique != 2 {
+		t.Errorf("found %d unique values, want 2", unique)
+	}
+}
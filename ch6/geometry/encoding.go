@@ -0,0 +1,204 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package geometry
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// geoJSON is the subset of a GeoJSON object needed to find a
+// LineString, possibly wrapped in a Feature.
+type geoJSON struct {
+	Type        string      `json:"type"`
+	Coordinates [][]float64 `json:"coordinates"`
+	Geometry    *geoJSON    `json:"geometry,omitempty"`
+}
+
+// GeoJSON returns the path encoded as a GeoJSON LineString geometry,
+// with X as longitude and Y as latitude.
+func (path Path) GeoJSON() ([]byte, error) {
+	g := geoJSON{Type: "LineString", Coordinates: [][]float64{}}
+	for _, p := range path {
+		g.Coordinates = append(g.Coordinates, []float64{p.X, p.Y})
+	}
+	return json.Marshal(g)
+}
+
+// ParseGeoJSON decodes a GeoJSON LineString geometry, or a Feature whose
+// geometry is a LineString, as a path.  Any altitude is discarded.
+func ParseGeoJSON(data []byte) (Path, error) {
+	var g geoJSON
+	if err := json.Unmarshal(data, &g); err != nil {
+		return nil, fmt.Errorf("geojson: %v", err)
+	}
+	if g.Type == "Feature" {
+		if g.Geometry == nil {
+			return nil, fmt.Errorf("geojson: feature has no geometry")
+		}
+		g = *g.Geometry
+	}
+	if g.Type != "LineString" {
+		return nil, fmt.Errorf("geojson: got %q, want LineString", g.Type)
+	}
+	path := make(Path, 0, len(g.Coordinates))
+	for i, c := range g.Coordinates {
+		if len(c) < 2 {
+			return nil, fmt.Errorf("geojson: position %d has %d coordinates", i, len(c))
+		}
+		path = append(path, Point{c[0], c[1]})
+	}
+	return path, nil
+}
+
+// SVG returns the path as SVG path data, suitable for the d attribute
+// of a <path> element: an absolute moveto followed by linetos.
+func (path Path) SVG() string {
+	var b strings.Builder
+	for i, p := range path {
+		if i == 0 {
+			b.WriteString("M")
+		} else {
+			b.WriteString(" L")
+		}
+		fmt.Fprintf(&b, "%g,%g", p.X, p.Y)
+	}
+	return b.String()
+}
+
+// ParseSVG decodes SVG path data made of straight lines: the commands
+// M, L, H, V and Z, in absolute (upper case) or relative (lower case)
+// form.  A Z command appends the start of the path to close it.
+// Curves and arcs, and data with more than one subpath, are rejected.
+func ParseSVG(d string) (Path, error) {
+	s := &svgScanner{s: d}
+	var path Path
+	var cur Point
+	var cmd byte
+	for {
+		s.skipSpace()
+		if s.i == len(s.s) {
+			break
+		}
+		if c := s.s[s.i]; isLetter(c) {
+			cmd = c
+			s.i++
+		} else if cmd == 0 {
+			return nil, fmt.Errorf("svg: path data must begin with a command")
+		} else if cmd == 'M' {
+			// Coordinates following a moveto are implicit linetos.
+			cmd = 'L'
+		} else if cmd == 'm' {
+			cmd = 'l'
+		}
+
+		rel := cmd >= 'a'
+		var next Point
+		switch cmd {
+		case 'M', 'm', 'L', 'l':
+			if (cmd == 'M' || cmd == 'm') && len(path) > 0 {
+				return nil, fmt.Errorf("svg: path data has more than one subpath")
+			}
+			x, err := s.number()
+			if err != nil {
+				return nil, err
+			}
+			y, err := s.number()
+			if err != nil {
+				return nil, err
+			}
+			next = Point{x, y}
+			if rel {
+				next = Point{cur.X + x, cur.Y + y}
+			}
+		case 'H', 'h':
+			x, err := s.number()
+			if err != nil {
+				return nil, err
+			}
+			next = Point{x, cur.Y}
+			if rel {
+				next.X += cur.X
+			}
+		case 'V', 'v':
+			y, err := s.number()
+			if err != nil {
+				return nil, err
+			}
+			next = Point{cur.X, y}
+			if rel {
+				next.Y += cur.Y
+			}
+		case 'Z', 'z':
+			if len(path) == 0 {
+				return nil, fmt.Errorf("svg: closepath before moveto")
+			}
+			path = append(path, path[0])
+			cur = path[0]
+			cmd = 0 // Z takes no arguments
+			continue
+		default:
+			return nil, fmt.Errorf("svg: unsupported command %q", cmd)
+		}
+		if len(path) == 0 && cmd != 'M' && cmd != 'm' {
+			return nil, fmt.Errorf("svg: path data must begin with a moveto")
+		}
+		path = append(path, next)
+		cur = next
+	}
+	return path, nil
+}
+
+// An svgScanner reads numbers from SVG path data.
+type svgScanner struct {
+	s string
+	i int
+}
+
+// skipSpace skips white space and commas.
+func (s *svgScanner) skipSpace() {
+	for s.i < len(s.s) && strings.IndexByte(" \t\r\n,", s.s[s.i]) >= 0 {
+		s.i++
+	}
+}
+
+// number reads the next number.  Numbers need not be separated
+// when the next begins with a sign or a second decimal point,
+// as in "10-5" or ".5.5".
+func (s *svgScanner) number() (float64, error) {
+	s.skipSpace()
+	start := s.i
+	if s.i < len(s.s) && (s.s[s.i] == '+' || s.s[s.i] == '-') {
+		s.i++
+	}
+	dot, exp := false, false
+	for s.i < len(s.s) {
+		c := s.s[s.i]
+		if '0' <= c && c <= '9' {
+			s.i++
+		} else if c == '.' && !dot && !exp {
+			dot = true
+			s.i++
+		} else if (c == 'e' || c == 'E') && !exp && s.i+1 < len(s.s) {
+			exp = true
+			s.i++
+			if c := s.s[s.i]; c == '+' || c == '-' {
+				s.i++
+			}
+		} else {
+			break
+		}
+	}
+	x, err := strconv.ParseFloat(s.s[start:s.i], 64)
+	if err != nil {
+		return 0, fmt.Errorf("svg: bad number at offset %d", start)
+	}
+	return x, nil
+}
+
+func isLetter(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
@@ -0,0 +1,171 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package geometry
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestPolygon(t *testing.T) {
+	square := Polygon{{0, 0}, {4, 0}, {4, 4}, {0, 4}}
+	if got := square.SignedArea(); got != 16 {
+		t.Errorf("SignedArea = %g, want 16", got)
+	}
+	reversed := Polygon{{0, 4}, {4, 4}, {4, 0}, {0, 0}}
+	if got := reversed.SignedArea(); got != -16 {
+		t.Errorf("reversed SignedArea = %g, want -16", got)
+	}
+	if got := reversed.Area(); got != 16 {
+		t.Errorf("reversed Area = %g, want 16", got)
+	}
+	if got := square.Perimeter(); got != 16 {
+		t.Errorf("Perimeter = %g, want 16", got)
+	}
+	if got := square.Centroid(); got != (Point{2, 2}) {
+		t.Errorf("Centroid = %v, want {2 2}", got)
+	}
+	// An L shape: its centroid lies outside the bounding box center.
+	ell := Polygon{{0, 0}, {2, 0}, {2, 1}, {1, 1}, {1, 2}, {0, 2}}
+	if got, want := ell.Centroid(), (Point{5.0 / 6, 5.0 / 6}); !near(got, want) {
+		t.Errorf("L Centroid = %v, want %v", got, want)
+	}
+	for _, test := range []struct {
+		p    Point
+		want bool
+	}{
+		{Point{0.5, 0.5}, true},
+		{Point{1.5, 0.5}, true},
+		{Point{1.5, 1.5}, false},
+		{Point{-1, 0.5}, false},
+		{Point{3, 3}, false},
+	} {
+		if got := ell.Contains(test.p); got != test.want {
+			t.Errorf("L.Contains(%v) = %t, want %t", test.p, got, test.want)
+		}
+	}
+	if got, want := ell.Bounds(), (Rect{Point{0, 0}, Point{2, 2}}); got != want {
+		t.Errorf("Bounds = %v, want %v", got, want)
+	}
+}
+
+func TestConvexHull(t *testing.T) {
+	points := []Point{{0, 0}, {2, 0}, {1, 1}, {2, 2}, {0, 2}, {1, 0}, {0, 2}}
+	hull := ConvexHull(points)
+	want := Polygon{{0, 0}, {2, 0}, {2, 2}, {0, 2}}
+	if len(hull) != len(want) {
+		t.Fatalf("ConvexHull = %v, want %v", hull, want)
+	}
+	for i := range want {
+		if hull[i] != want[i] {
+			t.Fatalf("ConvexHull = %v, want %v", hull, want)
+		}
+	}
+
+	// Degenerate inputs have no repeated vertices.
+	for _, test := range []struct {
+		points []Point
+		want   Polygon
+	}{
+		{nil, nil},
+		{[]Point{{1, 1}, {1, 1}, {1, 1}}, Polygon{{1, 1}}},
+		{[]Point{{2, 2}, {1, 1}, {2, 2}, {1, 1}}, Polygon{{1, 1}, {2, 2}}},
+	} {
+		if got := ConvexHull(test.points); !equalPoints(got, test.want) {
+			t.Errorf("ConvexHull(%v) = %v, want %v", test.points, got, test.want)
+		}
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		points := make([]Point, 1+rng.Intn(50))
+		for j := range points {
+			points[j] = Point{float64(rng.Intn(20)), float64(rng.Intn(20))}
+		}
+		hull := ConvexHull(points)
+		if len(hull) >= 3 {
+			if hull.SignedArea() <= 0 {
+				t.Fatalf("hull %v is not counterclockwise", hull)
+			}
+			for j := range hull {
+				for _, p := range points {
+					if cross(hull[j], hull[(j+1)%len(hull)], p) < 0 {
+						t.Fatalf("point %v lies outside hull %v", p, hull)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestSegmentIntersection(t *testing.T) {
+	for _, test := range []struct {
+		s, t Segment
+		want bool
+		at   Point
+	}{
+		{Segment{Point{0, 0}, Point{2, 2}}, Segment{Point{0, 2}, Point{2, 0}}, true, Point{1, 1}},
+		{Segment{Point{0, 0}, Point{1, 1}}, Segment{Point{1, 1}, Point{2, 0}}, true, Point{1, 1}},
+		{Segment{Point{0, 0}, Point{2, 0}}, Segment{Point{1, 0}, Point{1, 5}}, true, Point{1, 0}},
+		{Segment{Point{0, 0}, Point{1, 0}}, Segment{Point{1, 0}, Point{2, 0}}, true, Point{1, 0}},
+		{Segment{Point{0, 0}, Point{1, 1}}, Segment{Point{2, 2}, Point{3, 0}}, false, Point{}},
+		{Segment{Point{0, 0}, Point{1, 0}}, Segment{Point{0, 1}, Point{1, 1}}, false, Point{}},
+		{Segment{Point{0, 0}, Point{1, 0}}, Segment{Point{2, 0}, Point{3, 0}}, false, Point{}},
+		{Segment{Point{0, 0}, Point{2, 2}}, Segment{Point{1, 1}, Point{1, 1}}, true, Point{1, 1}},
+		{Segment{Point{1, 1}, Point{1, 1}}, Segment{Point{0, 0}, Point{2, 2}}, true, Point{1, 1}},
+		{Segment{Point{1, 1}, Point{1, 1}}, Segment{Point{1, 1}, Point{1, 1}}, true, Point{1, 1}},
+		{Segment{Point{0, 0}, Point{2, 2}}, Segment{Point{1, 2}, Point{1, 2}}, false, Point{}},
+	} {
+		if got := test.s.Intersects(test.t); got != test.want {
+			t.Errorf("%v.Intersects(%v) = %t, want %t", test.s, test.t, got, test.want)
+		}
+		at, ok := test.s.Intersection(test.t)
+		if ok != test.want || ok && !near(at, test.at) {
+			t.Errorf("%v.Intersection(%v) = %v, %t, want %v, %t",
+				test.s, test.t, at, ok, test.at, test.want)
+		}
+	}
+
+	// Overlapping collinear segments intersect, but not at a single point.
+	s := Segment{Point{0, 0}, Point{2, 0}}
+	u := Segment{Point{1, 0}, Point{3, 0}}
+	if !s.Intersects(u) {
+		t.Errorf("%v.Intersects(%v) = false", s, u)
+	}
+	if _, ok := s.Intersection(u); ok {
+		t.Errorf("%v.Intersection(%v) reported a single point", s, u)
+	}
+}
+
+func TestRect(t *testing.T) {
+	r := Rect{Point{0, 0}, Point{2, 2}}
+	s := Rect{Point{1, 1}, Point{3, 3}}
+	u := Rect{Point{5, 5}, Point{6, 6}}
+	if !r.Intersects(s) || r.Intersects(u) {
+		t.Errorf("Intersects: got %t, %t, want true, false", r.Intersects(s), r.Intersects(u))
+	}
+	if got, want := r.Intersect(s), (Rect{Point{1, 1}, Point{2, 2}}); got != want {
+		t.Errorf("Intersect = %v, want %v", got, want)
+	}
+	if got, want := r.Union(u), (Rect{Point{0, 0}, Point{6, 6}}); got != want {
+		t.Errorf("Union = %v, want %v", got, want)
+	}
+	if got := r.Union(Path(nil).Bounds()); got != r {
+		t.Errorf("Union with empty = %v, want %v", got, r)
+	}
+	if !r.Contains(Point{2, 2}) || r.Contains(Point{2, 2.1}) {
+		t.Errorf("Contains is wrong at the boundary")
+	}
+	if got := r.Distance(Point{5, 6}); got != 5 {
+		t.Errorf("Distance = %g, want 5", got)
+	}
+	if got := r.Distance(Point{1, 1}); got != 0 {
+		t.Errorf("Distance inside = %g, want 0", got)
+	}
+}
+
+func near(p, q Point) bool {
+	return math.Abs(p.X-q.X) < 1e-9 && math.Abs(p.Y-q.Y) < 1e-9
+}
@@ -0,0 +1,255 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package geometry
+
+import (
+	"container/heap"
+	"math"
+	"sort"
+)
+
+// A KDTree is a set of points, possibly with duplicates, organized
+// as a 2-d tree for fast nearest-neighbor and range queries.
+// The zero value is an empty tree.
+//
+// Points inserted one at a time after construction may unbalance
+// the tree; NewKDTree builds a balanced one.
+type KDTree struct {
+	root *kdNode
+	n    int
+}
+
+// A kdNode splits the plane along the X axis at even depths
+// and along the Y axis at odd depths.  Points whose coordinate
+// on the axis is less than that of p are to the left; the rest,
+// including ties, are to the right.
+type kdNode struct {
+	p           Point
+	left, right *kdNode
+}
+
+// NewKDTree returns a balanced tree containing the points.
+func NewKDTree(points []Point) *KDTree {
+	ps := append([]Point(nil), points...)
+	return &KDTree{root: build(ps, 0), n: len(ps)}
+}
+
+func build(ps []Point, depth int) *kdNode {
+	if len(ps) == 0 {
+		return nil
+	}
+	sort.Slice(ps, func(i, j int) bool {
+		return coord(ps[i], depth) < coord(ps[j], depth)
+	})
+	// Choose the median, moving left past ties so that
+	// all points equal to it on this axis go right.
+	m := len(ps) / 2
+	for m > 0 && coord(ps[m-1], depth) == coord(ps[m], depth) {
+		m--
+	}
+	return &kdNode{
+		p:     ps[m],
+		left:  build(ps[:m], depth+1),
+		right: build(ps[m+1:], depth+1),
+	}
+}
+
+// coord returns the coordinate of p on the axis used at the given depth.
+func coord(p Point, depth int) float64 {
+	if depth%2 == 0 {
+		return p.X
+	}
+	return p.Y
+}
+
+// Len returns the number of points in the tree.
+func (t *KDTree) Len() int { return t.n }
+
+// Insert adds the point p to the tree.
+func (t *KDTree) Insert(p Point) {
+	link := &t.root
+	for depth := 0; *link != nil; depth++ {
+		if coord(p, depth) < coord((*link).p, depth) {
+			link = &(*link).left
+		} else {
+			link = &(*link).right
+		}
+	}
+	*link = &kdNode{p: p}
+	t.n++
+}
+
+// Delete removes one occurrence of the point p from the tree,
+// and reports whether it was present.
+func (t *KDTree) Delete(p Point) bool {
+	var ok bool
+	t.root, ok = remove(t.root, p, 0)
+	if ok {
+		t.n--
+	}
+	return ok
+}
+
+// remove deletes p from the subtree rooted at n, at the given depth,
+// and returns the new root of the subtree.
+func remove(n *kdNode, p Point, depth int) (*kdNode, bool) {
+	if n == nil {
+		return nil, false
+	}
+	var ok bool
+	if n.p != p {
+		if coord(p, depth) < coord(n.p, depth) {
+			n.left, ok = remove(n.left, p, depth+1)
+		} else {
+			n.right, ok = remove(n.right, p, depth+1)
+		}
+		return n, ok
+	}
+	// Replace n's point by the minimum on its axis from the right
+	// subtree, which keeps all ties to the right.  If there is no
+	// right subtree, use the minimum of the left and move the left
+	// subtree to the right.
+	switch {
+	case n.right != nil:
+		n.p = findMin(n.right, depth%2, depth+1)
+		n.right, _ = remove(n.right, n.p, depth+1)
+	case n.left != nil:
+		n.p = findMin(n.left, depth%2, depth+1)
+		n.right, _ = remove(n.left, n.p, depth+1)
+		n.left = nil
+	default:
+		return nil, true
+	}
+	return n, true
+}
+
+// findMin returns the point with the least coordinate on the given
+// axis in the subtree rooted at n, at the given depth.
+func findMin(n *kdNode, axis, depth int) Point {
+	min := n.p
+	if depth%2 == axis {
+		// Only the left subtree can hold smaller values.
+		if n.left != nil {
+			min = findMin(n.left, axis, depth+1)
+		}
+		return min
+	}
+	for _, child := range []*kdNode{n.left, n.right} {
+		if child != nil {
+			if q := findMin(child, axis, depth+1); coord(q, axis) < coord(min, axis) {
+				min = q
+			}
+		}
+	}
+	return min
+}
+
+// Nearest returns the k points of the tree nearest to p, in order of
+// increasing distance.  It returns fewer if the tree has fewer than k.
+func (t *KDTree) Nearest(p Point, k int) []Point {
+	if k <= 0 {
+		return nil
+	}
+	h := &nearHeap{}
+	var visit func(n *kdNode, depth int)
+	visit = func(n *kdNode, depth int) {
+		if n == nil {
+			return
+		}
+		d := p.Distance(n.p)
+		if h.Len() < k {
+			heap.Push(h, neighbor{n.p, d})
+		} else if d < (*h)[0].dist {
+			(*h)[0] = neighbor{n.p, d}
+			heap.Fix(h, 0)
+		}
+		// Search the side containing p first; the other side
+		// can only help if the splitting line is closer than the
+		// kth nearest point found so far.
+		delta := coord(p, depth) - coord(n.p, depth)
+		near, far := n.left, n.right
+		if delta >= 0 {
+			near, far = far, near
+		}
+		visit(near, depth+1)
+		if h.Len() < k || math.Abs(delta) < (*h)[0].dist {
+			visit(far, depth+1)
+		}
+	}
+	visit(t.root, 0)
+
+	points := make([]Point, h.Len())
+	for i := len(points) - 1; i >= 0; i-- {
+		points[i] = heap.Pop(h).(neighbor).p
+	}
+	return points
+}
+
+// WithinRadius returns the points of the tree whose distance from p is
+// at most r, in no particular order.
+func (t *KDTree) WithinRadius(p Point, r float64) []Point {
+	var points []Point
+	var visit func(n *kdNode, depth int)
+	visit = func(n *kdNode, depth int) {
+		if n == nil {
+			return
+		}
+		if p.Distance(n.p) <= r {
+			points = append(points, n.p)
+		}
+		c, split := coord(p, depth), coord(n.p, depth)
+		if c-r < split {
+			visit(n.left, depth+1)
+		}
+		if c+r >= split {
+			visit(n.right, depth+1)
+		}
+	}
+	visit(t.root, 0)
+	return points
+}
+
+// InRect returns the points of the tree that lie in the rectangle r,
+// in no particular order.
+func (t *KDTree) InRect(r Rect) []Point {
+	var points []Point
+	var visit func(n *kdNode, depth int)
+	visit = func(n *kdNode, depth int) {
+		if n == nil {
+			return
+		}
+		if r.Contains(n.p) {
+			points = append(points, n.p)
+		}
+		split := coord(n.p, depth)
+		if coord(r.Min, depth) < split {
+			visit(n.left, depth+1)
+		}
+		if coord(r.Max, depth) >= split {
+			visit(n.right, depth+1)
+		}
+	}
+	visit(t.root, 0)
+	return points
+}
+
+type neighbor struct {
+	p    Point
+	dist float64
+}
+
+// A nearHeap is a max-heap of neighbors ordered by distance,
+// so that the farthest of the nearest k is at the root.
+type nearHeap []neighbor
+
+func (h nearHeap) Len() int            { return len(h) }
+func (h nearHeap) Less(i, j int) bool  { return h[i].dist > h[j].dist }
+func (h nearHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *nearHeap) Push(x interface{}) { *h = append(*h, x.(neighbor)) }
+func (h *nearHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
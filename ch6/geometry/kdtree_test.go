@@ -0,0 +1,138 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package geometry
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+// sortPoints sorts points by X then Y, for comparing results
+// that are returned in no particular order.
+func sortPoints(ps []Point) []Point {
+	sort.Slice(ps, func(i, j int) bool {
+		if ps[i].X != ps[j].X {
+			return ps[i].X < ps[j].X
+		}
+		return ps[i].Y < ps[j].Y
+	})
+	return ps
+}
+
+func equalPoints(x, y []Point) bool {
+	if len(x) != len(y) {
+		return false
+	}
+	for i := range x {
+		if x[i] != y[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// randomPoints returns n points on a small integer grid,
+// so that duplicates and ties on each axis are common.
+func randomPoints(rng *rand.Rand, n int) []Point {
+	ps := make([]Point, n)
+	for i := range ps {
+		ps[i] = Point{float64(rng.Intn(30)), float64(rng.Intn(30))}
+	}
+	return ps
+}
+
+func TestKDTree(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for trial := 0; trial < 50; trial++ {
+		points := randomPoints(rng, rng.Intn(300))
+		var tree *KDTree
+		if trial%2 == 0 {
+			tree = NewKDTree(points)
+		} else {
+			tree = new(KDTree)
+			for _, p := range points {
+				tree.Insert(p)
+			}
+		}
+
+		// Delete a random subset, keeping the brute-force copy in step.
+		for i := 0; i < len(points)/3; i++ {
+			j := rng.Intn(len(points))
+			if !tree.Delete(points[j]) {
+				t.Fatalf("Delete(%v) = false, want true", points[j])
+			}
+			points = append(points[:j], points[j+1:]...)
+		}
+		if tree.Delete(Point{-1, -1}) {
+			t.Fatalf("Delete of absent point = true")
+		}
+		if tree.Len() != len(points) {
+			t.Fatalf("Len = %d, want %d", tree.Len(), len(points))
+		}
+
+		for q := 0; q < 20; q++ {
+			p := Point{rng.Float64() * 30, rng.Float64() * 30}
+			checkQueries(t, tree, points, p, rng)
+		}
+	}
+}
+
+func checkQueries(t *testing.T, tree *KDTree, points []Point, p Point, rng *rand.Rand) {
+	t.Helper()
+
+	// Nearest: compare distances, since ties may be broken differently.
+	k := 1 + rng.Intn(10)
+	got := tree.Nearest(p, k)
+	byDist := append([]Point(nil), points...)
+	sort.SliceStable(byDist, func(i, j int) bool {
+		return p.Distance(byDist[i]) < p.Distance(byDist[j])
+	})
+	if len(byDist) > k {
+		byDist = byDist[:k]
+	}
+	if len(got) != len(byDist) {
+		t.Fatalf("Nearest(%v, %d) returned %d points, want %d", p, k, len(got), len(byDist))
+	}
+	for i := range got {
+		if p.Distance(got[i]) != p.Distance(byDist[i]) {
+			t.Fatalf("Nearest(%v, %d) = %v, want %v", p, k, got, byDist)
+		}
+	}
+
+	r := rng.Float64() * 8
+	var want []Point
+	for _, q := range points {
+		if p.Distance(q) <= r {
+			want = append(want, q)
+		}
+	}
+	if got := tree.WithinRadius(p, r); !equalPoints(sortPoints(got), sortPoints(want)) {
+		t.Fatalf("WithinRadius(%v, %g) = %v, want %v", p, r, got, want)
+	}
+
+	rect := Rect{p, Point{p.X + rng.Float64()*10, p.Y + rng.Float64()*10}}
+	want = nil
+	for _, q := range points {
+		if rect.Contains(q) {
+			want = append(want, q)
+		}
+	}
+	if got := tree.InRect(rect); !equalPoints(sortPoints(got), sortPoints(want)) {
+		t.Fatalf("InRect(%v) = %v, want %v", rect, got, want)
+	}
+}
+
+func BenchmarkKDTreeNearest(b *testing.B) {
+	rng := rand.New(rand.NewSource(1))
+	points := make([]Point, 1e6)
+	for i := range points {
+		points[i] = Point{rng.Float64(), rng.Float64()}
+	}
+	tree := NewKDTree(points)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tree.Nearest(Point{rng.Float64(), rng.Float64()}, 10)
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package geometry
+
+import "math"
+
+// Reverse returns a new path visiting the points of path in reverse order.
+func (path Path) Reverse() Path {
+	rev := make(Path, len(path))
+	for i, p := range path {
+		rev[len(path)-1-i] = p
+	}
+	return rev
+}
+
+// Split divides the path at arc length d from its start, returning
+// the part before and the part after.  The point at which it is
+// split ends the first part and begins the second, and is
+// interpolated if it falls within a segment.  If d is not positive,
+// the first part is just the start of the path; if d is at least
+// the path's length, the second part is just its end.
+func (path Path) Split(d float64) (Path, Path) {
+	if len(path) == 0 {
+		return nil, nil
+	}
+	d = math.Max(d, 0)
+	for i := 1; i < len(path); i++ {
+		seg := path[i-1].Distance(path[i])
+		if d < seg {
+			p := lerp(path[i-1], path[i], d/seg)
+			head := append(append(Path(nil), path[:i]...), p)
+			tail := append(Path{p}, path[i:]...)
+			if p == path[i-1] {
+				head = head[:len(head)-1]
+			}
+			return head, tail
+		}
+		d -= seg
+	}
+	last := path[len(path)-1]
+	return append(Path(nil), path...), Path{last}
+}
+
+// Resample returns a path of n points spaced at equal arc lengths along
+// path, beginning and ending at its ends.  If n is less than 2, or the
+// path has fewer than two points, Resample returns a copy of path.
+func (path Path) Resample(n int) Path {
+	if n < 2 || len(path) < 2 {
+		return append(Path(nil), path...)
+	}
+	step := path.Distance() / float64(n-1)
+	out := make(Path, 0, n)
+	out = append(out, path[0])
+	i, done := 1, 0.0 // done is arc length up to path[i-1]
+	for k := 1; k < n-1; k++ {
+		target := float64(k) * step
+		seg := path[i-1].Distance(path[i])
+		for done+seg < target && i < len(path)-1 {
+			done += seg
+			i++
+			seg = path[i-1].Distance(path[i])
+		}
+		t := 0.0
+		if seg > 0 {
+			t = math.Min((target-done)/seg, 1)
+		}
+		out = append(out, lerp(path[i-1], path[i], t))
+	}
+	return append(out, path[len(path)-1])
+}
+
+// Simplify returns a path approximating path with fewer points, using
+// the Douglas–Peucker algorithm: no point of the original path is
+// farther than epsilon from the simplified one.  The ends are kept.
+func (path Path) Simplify(epsilon float64) Path {
+	if len(path) < 3 {
+		return append(Path(nil), path...)
+	}
+	keep := make([]bool, len(path))
+	keep[0], keep[len(path)-1] = true, true
+
+	// Use an explicit stack of spans so long traces
+	// don't recurse deeply.
+	type span struct{ lo, hi int }
+	stack := []span{{0, len(path) - 1}}
+	for len(stack) > 0 {
+		s := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		seg := Segment{path[s.lo], path[s.hi]}
+		max, at := -1.0, -1
+		for i := s.lo + 1; i < s.hi; i++ {
+			if d := seg.Distance(path[i]); d > max {
+				max, at = d, i
+			}
+		}
+		if at >= 0 && max > epsilon {
+			keep[at] = true
+			stack = append(stack, span{s.lo, at}, span{at, s.hi})
+		}
+	}
+
+	var out Path
+	for i, p := range path {
+		if keep[i] {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// Distance returns the distance from p to the nearest point of the segment.
+func (s Segment) Distance(p Point) float64 {
+	dx, dy := s.B.X-s.A.X, s.B.Y-s.A.Y
+	len2 := dx*dx + dy*dy
+	if len2 == 0 {
+		return p.Distance(s.A)
+	}
+	t := ((p.X-s.A.X)*dx + (p.Y-s.A.Y)*dy) / len2
+	t = math.Max(0, math.Min(1, t))
+	return p.Distance(lerp(s.A, s.B, t))
+}
+
+// lerp returns the point a fraction t of the way from p to q.
+func lerp(p, q Point, t float64) Point {
+	return Point{p.X + t*(q.X-p.X), p.Y + t*(q.Y-p.Y)}
+}
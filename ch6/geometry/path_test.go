@@ -0,0 +1,139 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package geometry
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestReverseSplit(t *testing.T) {
+	path := Path{{0, 0}, {3, 0}, {3, 4}}
+	if got, want := path.Reverse(), (Path{{3, 4}, {3, 0}, {0, 0}}); !equalPoints(got, want) {
+		t.Errorf("Reverse = %v, want %v", got, want)
+	}
+	for _, test := range []struct {
+		d          float64
+		head, tail Path
+	}{
+		{-1, Path{{0, 0}}, Path{{0, 0}, {3, 0}, {3, 4}}},
+		{1, Path{{0, 0}, {1, 0}}, Path{{1, 0}, {3, 0}, {3, 4}}},
+		{3, Path{{0, 0}, {3, 0}}, Path{{3, 0}, {3, 4}}},
+		{5, Path{{0, 0}, {3, 0}, {3, 2}}, Path{{3, 2}, {3, 4}}},
+		{7, Path{{0, 0}, {3, 0}, {3, 4}}, Path{{3, 4}}},
+	} {
+		head, tail := path.Split(test.d)
+		if !equalPoints(head, test.head) || !equalPoints(tail, test.tail) {
+			t.Errorf("Split(%g) = %v, %v, want %v, %v",
+				test.d, head, tail, test.head, test.tail)
+		}
+	}
+}
+
+func TestResample(t *testing.T) {
+	path := Path{{0, 0}, {3, 0}, {3, 4}, {3, 4}, {0, 4}}
+	got := path.Resample(6)
+	want := Path{{0, 0}, {2, 0}, {3, 1}, {3, 3}, {2, 4}, {0, 4}}
+	if len(got) != len(want) {
+		t.Fatalf("Resample = %v, want %v", got, want)
+	}
+	for i := range want {
+		if !near(got[i], want[i]) {
+			t.Fatalf("Resample = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestSimplify(t *testing.T) {
+	path := Path{{0, 0}, {1, 0.1}, {2, -0.1}, {3, 5}, {4, 6}, {5, 7}}
+	got := path.Simplify(0.5)
+	want := Path{{0, 0}, {2, -0.1}, {3, 5}, {5, 7}}
+	if !equalPoints(got, want) {
+		t.Errorf("Simplify = %v, want %v", got, want)
+	}
+
+	// No original point is farther than epsilon from the result.
+	rng := rand.New(rand.NewSource(1))
+	walk := Path{{0, 0}}
+	for i := 0; i < 500; i++ {
+		last := walk[len(walk)-1]
+		walk = append(walk, Point{last.X + rng.Float64(), last.Y + rng.NormFloat64()})
+	}
+	for _, eps := range []float64{0.1, 1, 10} {
+		simple := walk.Simplify(eps)
+		for _, p := range walk {
+			d := math.Inf(1)
+			for i := 1; i < len(simple); i++ {
+				d = math.Min(d, Segment{simple[i-1], simple[i]}.Distance(p))
+			}
+			if d > eps {
+				t.Fatalf("Simplify(%g): %v is %g from result", eps, p, d)
+			}
+		}
+	}
+}
+
+func TestGeoJSON(t *testing.T) {
+	path := Path{{-0.1276, 51.5072}, {2.3522, 48.8566}}
+	data, err := path.GeoJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = `{"type":"LineString","coordinates":[[-0.1276,51.5072],[2.3522,48.8566]]}`
+	if string(data) != want {
+		t.Errorf("GeoJSON = %s, want %s", data, want)
+	}
+	got, err := ParseGeoJSON(data)
+	if err != nil || !equalPoints(got, path) {
+		t.Errorf("ParseGeoJSON(%s) = %v, %v", data, got, err)
+	}
+
+	feature := `{"type":"Feature","properties":{"name":"trace"},
+		"geometry":{"type":"LineString","coordinates":[[1,2,30],[3,4,40]]}}`
+	if got, err := ParseGeoJSON([]byte(feature)); err != nil || !equalPoints(got, Path{{1, 2}, {3, 4}}) {
+		t.Errorf("ParseGeoJSON(feature) = %v, %v", got, err)
+	}
+
+	for _, bad := range []string{
+		`{"type":"Point","coordinates":[1,2]}`,
+		`{"type":"Feature"}`,
+		`{"type":"LineString","coordinates":[[1]]}`,
+		`[`,
+	} {
+		if _, err := ParseGeoJSON([]byte(bad)); err == nil {
+			t.Errorf("ParseGeoJSON(%s) succeeded, want error", bad)
+		}
+	}
+}
+
+func TestSVG(t *testing.T) {
+	path := Path{{0, 0}, {1.5, -2}, {3, 4}}
+	const want = "M0,0 L1.5,-2 L3,4"
+	if got := path.SVG(); got != want {
+		t.Errorf("SVG = %q, want %q", got, want)
+	}
+	if got, err := ParseSVG(want); err != nil || !equalPoints(got, path) {
+		t.Errorf("ParseSVG(%q) = %v, %v", want, got, err)
+	}
+
+	for _, test := range []struct {
+		d    string
+		want Path
+	}{
+		{"M 10 10 H 20 V 20 Z", Path{{10, 10}, {20, 10}, {20, 20}, {10, 10}}},
+		{"m1,1 2,0 l0,2 h-2z", Path{{1, 1}, {3, 1}, {3, 3}, {1, 3}, {1, 1}}},
+		{"M1e1-5L.5.5", Path{{10, -5}, {0.5, 0.5}}},
+	} {
+		if got, err := ParseSVG(test.d); err != nil || !equalPoints(got, test.want) {
+			t.Errorf("ParseSVG(%q) = %v, %v, want %v", test.d, got, err, test.want)
+		}
+	}
+
+	for _, bad := range []string{"L1,1", "1,1", "M0,0 C1,1 2,2 3,3", "M0,0 M1,1", "M0", "M0,x"} {
+		if _, err := ParseSVG(bad); err == nil {
+			t.Errorf("ParseSVG(%q) succeeded, want error", bad)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package geometry
+
+import (
+	"math"
+	"sort"
+)
+
+// A Polygon is a closed path whose last point connects to its first.
+// Its vertices may be listed in either direction, but its edges
+// should not cross.
+type Polygon []Point
+
+// Edges returns the edges of the polygon.
+func (poly Polygon) Edges() []Segment {
+	edges := make([]Segment, len(poly))
+	for i := range poly {
+		edges[i] = Segment{poly[i], poly[(i+1)%len(poly)]}
+	}
+	return edges
+}
+
+// SignedArea returns the area enclosed by the polygon, computed
+// by the shoelace formula.  It is positive if the vertices are
+// listed counterclockwise and negative if clockwise.
+func (poly Polygon) SignedArea() float64 {
+	sum := 0.0
+	for i, p := range poly {
+		q := poly[(i+1)%len(poly)]
+		sum += p.X*q.Y - q.X*p.Y
+	}
+	return sum / 2
+}
+
+// Area returns the area enclosed by the polygon.
+func (poly Polygon) Area() float64 {
+	return math.Abs(poly.SignedArea())
+}
+
+// Perimeter returns the length of the boundary of the polygon.
+func (poly Polygon) Perimeter() float64 {
+	if len(poly) == 0 {
+		return 0
+	}
+	return Path(poly).Distance() + poly[len(poly)-1].Distance(poly[0])
+}
+
+// Centroid returns the center of mass of the region enclosed by
+// the polygon.  For a degenerate polygon of zero area, it returns
+// the mean of the vertices.
+func (poly Polygon) Centroid() Point {
+	var c Point
+	a := poly.SignedArea()
+	if a == 0 {
+		for _, p := range poly {
+			c.X += p.X
+			c.Y += p.Y
+		}
+		if n := float64(len(poly)); n > 0 {
+			c.X /= n
+			c.Y /= n
+		}
+		return c
+	}
+	for i, p := range poly {
+		q := poly[(i+1)%len(poly)]
+		cross := p.X*q.Y - q.X*p.Y
+		c.X += (p.X + q.X) * cross
+		c.Y += (p.Y + q.Y) * cross
+	}
+	c.X /= 6 * a
+	c.Y /= 6 * a
+	return c
+}
+
+// Contains reports whether p lies inside the polygon,
+// using the even-odd rule.  Points on the boundary may be
+// reported as either inside or outside.
+func (poly Polygon) Contains(p Point) bool {
+	inside := false
+	for i, a := range poly {
+		b := poly[(i+1)%len(poly)]
+		// Does a horizontal ray from p to the right cross edge ab?
+		if (a.Y > p.Y) != (b.Y > p.Y) &&
+			p.X < a.X+(p.Y-a.Y)*(b.X-a.X)/(b.Y-a.Y) {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// Bounds returns the smallest rectangle containing the polygon.
+func (poly Polygon) Bounds() Rect {
+	return Path(poly).Bounds()
+}
+
+// ConvexHull returns the smallest convex polygon containing the
+// points, with its vertices in counterclockwise order, computed
+// by Andrew's monotone chain algorithm.  Collinear points on the
+// boundary are omitted.
+func ConvexHull(points []Point) Polygon {
+	ps := append([]Point(nil), points...)
+	sort.Slice(ps, func(i, j int) bool {
+		if ps[i].X != ps[j].X {
+			return ps[i].X < ps[j].X
+		}
+		return ps[i].Y < ps[j].Y
+	})
+	ps = dedup(ps)
+	if len(ps) < 3 {
+		return ps
+	}
+
+	// Build the lower hull, then the upper hull.
+	hull := make(Polygon, 0, 2*len(ps))
+	for pass := 0; pass < 2; pass++ {
+		start := len(hull)
+		for _, p := range ps {
+			for len(hull) >= start+2 && cross(hull[len(hull)-2], hull[len(hull)-1], p) <= 0 {
+				hull = hull[:len(hull)-1]
+			}
+			hull = append(hull, p)
+		}
+		hull = hull[:len(hull)-1] // last point is first of the other hull
+		for i, j := 0, len(ps)-1; i < j; i, j = i+1, j-1 {
+			ps[i], ps[j] = ps[j], ps[i]
+		}
+	}
+	return hull
+}
+
+// dedup returns the sorted points ps without duplicates.
+func dedup(ps []Point) Polygon {
+	var out Polygon
+	for i, p := range ps {
+		if i == 0 || p != ps[i-1] {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// cross returns the z component of the cross product of the
+// vectors oa and ob, which is positive if o, a, b make a
+// counterclockwise turn, negative if clockwise, and zero if
+// they are collinear.
+func cross(o, a, b Point) float64 {
+	return (a.X-o.X)*(b.Y-o.Y) - (a.Y-o.Y)*(b.X-o.X)
+}
@@ -0,0 +1,74 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package geometry
+
+import "math"
+
+// A Rect is an axis-aligned rectangle containing the points
+// whose coordinates lie between those of Min and Max inclusive.
+// A Rect whose Min exceeds its Max in either coordinate is empty.
+type Rect struct{ Min, Max Point }
+
+// Empty reports whether the rectangle contains no points.
+func (r Rect) Empty() bool {
+	return r.Min.X > r.Max.X || r.Min.Y > r.Max.Y
+}
+
+// Contains reports whether p lies in the rectangle.
+func (r Rect) Contains(p Point) bool {
+	return r.Min.X <= p.X && p.X <= r.Max.X &&
+		r.Min.Y <= p.Y && p.Y <= r.Max.Y
+}
+
+// Intersects reports whether r and s have at least one point in common.
+func (r Rect) Intersects(s Rect) bool {
+	return !r.Intersect(s).Empty()
+}
+
+// Intersect returns the largest rectangle contained by both r and s,
+// which is empty if they do not intersect.
+func (r Rect) Intersect(s Rect) Rect {
+	return Rect{
+		Point{math.Max(r.Min.X, s.Min.X), math.Max(r.Min.Y, s.Min.Y)},
+		Point{math.Min(r.Max.X, s.Max.X), math.Min(r.Max.Y, s.Max.Y)},
+	}
+}
+
+// Union returns the smallest rectangle containing both r and s.
+func (r Rect) Union(s Rect) Rect {
+	if r.Empty() {
+		return s
+	}
+	if s.Empty() {
+		return r
+	}
+	return Rect{
+		Point{math.Min(r.Min.X, s.Min.X), math.Min(r.Min.Y, s.Min.Y)},
+		Point{math.Max(r.Max.X, s.Max.X), math.Max(r.Max.Y, s.Max.Y)},
+	}
+}
+
+// Distance returns the distance from p to the nearest point of the
+// rectangle, which is zero if p lies in it.
+func (r Rect) Distance(p Point) float64 {
+	dx := math.Max(0, math.Max(r.Min.X-p.X, p.X-r.Max.X))
+	dy := math.Max(0, math.Max(r.Min.Y-p.Y, p.Y-r.Max.Y))
+	return math.Hypot(dx, dy)
+}
+
+// Bounds returns the smallest rectangle containing the path.
+// The bounds of an empty path are empty.
+func (path Path) Bounds() Rect {
+	r := Rect{
+		Point{math.Inf(+1), math.Inf(+1)},
+		Point{math.Inf(-1), math.Inf(-1)},
+	}
+	for _, p := range path {
+		r.Min.X = math.Min(r.Min.X, p.X)
+		r.Min.Y = math.Min(r.Min.Y, p.Y)
+		r.Max.X = math.Max(r.Max.X, p.X)
+		r.Max.Y = math.Max(r.Max.Y, p.Y)
+	}
+	return r
+}
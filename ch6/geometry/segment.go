@@ -0,0 +1,83 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package geometry
+
+import "math"
+
+// A Segment is the straight line segment between two points.
+type Segment struct{ A, B Point }
+
+// Length returns the length of the segment.
+func (s Segment) Length() float64 {
+	return s.A.Distance(s.B)
+}
+
+// Intersects reports whether the segments s and t have
+// at least one point in common.
+func (s Segment) Intersects(t Segment) bool {
+	d1 := cross(t.A, t.B, s.A)
+	d2 := cross(t.A, t.B, s.B)
+	d3 := cross(s.A, s.B, t.A)
+	d4 := cross(s.A, s.B, t.B)
+	if (d1 > 0 && d2 < 0 || d1 < 0 && d2 > 0) &&
+		(d3 > 0 && d4 < 0 || d3 < 0 && d4 > 0) {
+		return true // proper crossing
+	}
+	// An endpoint of one segment lies on the other.
+	return d1 == 0 && onSegment(t, s.A) ||
+		d2 == 0 && onSegment(t, s.B) ||
+		d3 == 0 && onSegment(s, t.A) ||
+		d4 == 0 && onSegment(s, t.B)
+}
+
+// Intersection returns the point at which the segments s and t
+// intersect.  It reports false if they do not intersect, or if
+// they are collinear and overlap in more than one point.
+func (s Segment) Intersection(t Segment) (Point, bool) {
+	if !s.Intersects(t) {
+		return Point{}, false
+	}
+	// A zero-length segment meets the other only at its one point.
+	if s.A == s.B {
+		return s.A, true
+	}
+	if t.A == t.B {
+		return t.A, true
+	}
+	r := Point{s.B.X - s.A.X, s.B.Y - s.A.Y}
+	q := Point{t.B.X - t.A.X, t.B.Y - t.A.Y}
+	denom := r.X*q.Y - r.Y*q.X
+	if denom == 0 {
+		// Collinear: they meet at a point only if they share
+		// just an endpoint.
+		for _, p := range []Point{s.A, s.B} {
+			if (p == t.A || p == t.B) && s.overlapLength(t) == 0 {
+				return p, true
+			}
+		}
+		return Point{}, false
+	}
+	u := ((t.A.X-s.A.X)*q.Y - (t.A.Y-s.A.Y)*q.X) / denom
+	return Point{s.A.X + u*r.X, s.A.Y + u*r.Y}, true
+}
+
+// overlapLength returns the length of the overlap of the
+// collinear segments s and t.
+func (s Segment) overlapLength(t Segment) float64 {
+	// Project onto the segment's dominant axis.
+	proj := func(p Point) float64 { return p.X }
+	if math.Abs(s.B.Y-s.A.Y) > math.Abs(s.B.X-s.A.X) {
+		proj = func(p Point) float64 { return p.Y }
+	}
+	lo := math.Max(math.Min(proj(s.A), proj(s.B)), math.Min(proj(t.A), proj(t.B)))
+	hi := math.Min(math.Max(proj(s.A), proj(s.B)), math.Max(proj(t.A), proj(t.B)))
+	return math.Max(0, hi-lo)
+}
+
+// onSegment reports whether p, known to be collinear with s,
+// lies within its bounding box.
+func onSegment(s Segment, p Point) bool {
+	return math.Min(s.A.X, s.B.X) <= p.X && p.X <= math.Max(s.A.X, s.B.X) &&
+		math.Min(s.A.Y, s.B.Y) <= p.Y && p.Y <= math.Max(s.A.Y, s.B.Y)
+}
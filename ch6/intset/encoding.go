@@ -0,0 +1,269 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package intset
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Binary encoding.
+//
+// The encoding begins with a version byte and a kind byte.
+// For kind elemsKind, there follows the number of elements
+// and the differences between successive elements (the first
+// from -1), all as uvarints; this suits sparse sets.
+// For kind wordsKind, there follows a sequence of groups of
+// non-zero words, each encoded as the uvarint number of zero
+// words skipped before the group, the uvarint number of words
+// in the group, and the words as 8 little-endian bytes; this
+// suits dense sets.  MarshalBinary uses whichever is shorter.
+const (
+	binaryVersion = 1
+
+	elemsKind = 0
+	wordsKind = 1
+)
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (s *IntSet) MarshalBinary() ([]byte, error) {
+	elems := []byte{binaryVersion, elemsKind}
+	elems = appendUvarint(elems, uint64(s.Len()))
+	prev := -1
+	s.Each(func(x int) bool {
+		elems = appendUvarint(elems, uint64(x-prev))
+		prev = x
+		return true
+	})
+
+	words := []byte{binaryVersion, wordsKind}
+	for i := 0; i < len(s.words); {
+		// Skip zero words, then take the following non-zero words.
+		j := i
+		for j < len(s.words) && s.words[j] == 0 {
+			j++
+		}
+		if j == len(s.words) {
+			break
+		}
+		k := j
+		for k < len(s.words) && s.words[k] != 0 {
+			k++
+		}
+		words = appendUvarint(words, uint64(j-i))
+		words = appendUvarint(words, uint64(k-j))
+		for _, word := range s.words[j:k] {
+			var buf [8]byte
+			binary.LittleEndian.PutUint64(buf[:], word)
+			words = append(words, buf[:]...)
+		}
+		i = k
+	}
+
+	if len(words) < len(elems) {
+		return words, nil
+	}
+	return elems, nil
+}
+
+func appendUvarint(buf []byte, x uint64) []byte {
+	var tmp [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(tmp[:], x)
+	return append(buf, tmp[:n]...)
+}
+
+var errCorrupt = errors.New("intset: corrupt binary encoding")
+
+// maxDecoded is the largest element accepted by UnmarshalBinary,
+// UnmarshalJSON and Parse, so that a short crafted input cannot
+// make them allocate without limit.  A set holding it occupies
+// 128MiB.
+const maxDecoded = 1<<30 - 1
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+// It replaces the contents of s.  It rejects elements
+// greater than 1<<30 - 1.
+func (s *IntSet) UnmarshalBinary(data []byte) error {
+	if len(data) < 2 {
+		return errCorrupt
+	}
+	if data[0] != binaryVersion {
+		return fmt.Errorf("intset: unsupported binary encoding version %d", data[0])
+	}
+	r := bytes.NewReader(data[2:])
+	var t IntSet
+	switch data[1] {
+	case elemsKind:
+		n, err := binary.ReadUvarint(r)
+		if err != nil || n > uint64(r.Len()) {
+			return errCorrupt
+		}
+		var next uint64 // one more than the previous element
+		for ; n > 0; n-- {
+			delta, err := binary.ReadUvarint(r)
+			if err != nil || delta == 0 || delta-1 > maxDecoded || next+delta-1 > maxDecoded {
+				return errCorrupt
+			}
+			x := next + delta - 1
+			t.Add(int(x))
+			next = x + 1
+		}
+
+	case wordsKind:
+		for r.Len() > 0 {
+			// Check the implied number of words before allocating.
+			skip, err := binary.ReadUvarint(r)
+			if err != nil || skip > maxDecoded/64+1-uint64(len(t.words)) {
+				return errCorrupt
+			}
+			n, err := binary.ReadUvarint(r)
+			if err != nil || n == 0 || n > uint64(r.Len()/8) ||
+				n > maxDecoded/64+1-uint64(len(t.words))-skip {
+				return errCorrupt
+			}
+			t.words = append(t.words, make([]uint64, skip)...)
+			for ; n > 0; n-- {
+				var buf [8]byte
+				r.Read(buf[:])
+				t.words = append(t.words, binary.LittleEndian.Uint64(buf[:]))
+			}
+		}
+		t.count()
+
+	default:
+		return fmt.Errorf("intset: unknown binary encoding kind %d", data[1])
+	}
+	if r.Len() > 0 {
+		return errCorrupt
+	}
+	*s = t
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler.
+// The set is encoded as an array of its elements in increasing order.
+func (s *IntSet) MarshalJSON() ([]byte, error) {
+	elems := s.Elems()
+	if elems == nil {
+		elems = []int{} // not null
+	}
+	return json.Marshal(elems)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+// It replaces the contents of s with the elements of a JSON array,
+// which must not exceed 1<<30 - 1.
+func (s *IntSet) UnmarshalJSON(data []byte) error {
+	var elems []int
+	if err := json.Unmarshal(data, &elems); err != nil {
+		return err
+	}
+	var t IntSet
+	for _, x := range elems {
+		if x < 0 || x > maxDecoded {
+			return fmt.Errorf("intset: element %d out of range", x)
+		}
+		t.Add(x)
+	}
+	*s = t
+	return nil
+}
+
+// Parse parses a set in the form produced by String, such as
+// "{1 2 144}", which may also contain inclusive ranges of elements,
+// as in "{1-100 200}".  Elements must not exceed 1<<30 - 1.
+func Parse(str string) (*IntSet, error) {
+	body := strings.TrimSpace(str)
+	if !strings.HasPrefix(body, "{") || !strings.HasSuffix(body, "}") {
+		return nil, fmt.Errorf("intset: parsing %q: missing braces", str)
+	}
+	body = body[1 : len(body)-1]
+	var s IntSet
+	for _, field := range strings.Fields(body) {
+		lo, hi := field, field
+		if i := strings.IndexByte(field, '-'); i >= 0 {
+			lo, hi = field[:i], field[i+1:]
+		}
+		x, err := strconv.ParseUint(lo, 10, 0)
+		if err != nil {
+			return nil, fmt.Errorf("intset: parsing %q: bad element %q", str, field)
+		}
+		y, err := strconv.ParseUint(hi, 10, 0)
+		if err != nil || y < x || y > maxDecoded {
+			return nil, fmt.Errorf("intset: parsing %q: bad element %q", str, field)
+		}
+		s.addRange(int(x), int(y))
+	}
+	s.count()
+	return &s, nil
+}
+
+// addRange adds the elements lo to hi inclusive to the set a word
+// at a time.  It does not update the cached count.
+func (s *IntSet) addRange(lo, hi int) {
+	for hi/64 >= len(s.words) {
+		s.words = append(s.words, 0)
+	}
+	for i := lo / 64; i <= hi/64; i++ {
+		mask := ^uint64(0)
+		if i == lo/64 {
+			mask &^= 1<<uint(lo%64) - 1
+		}
+		if i == hi/64 && hi%64 != 63 {
+			mask &= 1<<uint(hi%64+1) - 1
+		}
+		s.words[i] |= mask
+	}
+}
+
+// maxFormat is the number of elements printed by fmt's %v and %s
+// verbs before the output is truncated.
+const maxFormat = 100
+
+// Format implements fmt.Formatter.  The %v and %s verbs print the
+// set as String does, but truncate sets of more than 100 elements,
+// or of more elements than the precision if one is given, thus:
+// "{0 1 2 ...(997 more)}".  The %+v verb prints every element.
+func (s *IntSet) Format(f fmt.State, verb rune) {
+	switch verb {
+	case 'v', 's':
+	default:
+		fmt.Fprintf(f, "%%!%c(*intset.IntSet=%s)", verb, s.String())
+		return
+	}
+	limit, ok := f.Precision()
+	if !ok {
+		limit = maxFormat
+	}
+	if verb == 'v' && f.Flag('+') {
+		limit = s.Len()
+	}
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	n := 0
+	s.Each(func(x int) bool {
+		if n == limit {
+			return false
+		}
+		if n > 0 {
+			buf.WriteByte(' ')
+		}
+		fmt.Fprintf(&buf, "%d", x)
+		n++
+		return true
+	})
+	if n < s.Len() {
+		if n > 0 {
+			buf.WriteByte(' ')
+		}
+		fmt.Fprintf(&buf, "...(%d more)", s.Len()-n)
+	}
+	buf.WriteByte('}')
+	f.Write(buf.Bytes())
+}
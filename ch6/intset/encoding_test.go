@@ -0,0 +1,220 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package intset
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+func ExampleParse() {
+	s, err := Parse("{1-5 64 200-202}")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println(s)
+	// Output:
+	// {1 2 3 4 5 64 200 201 202}
+}
+
+func ExampleIntSet_Format() {
+	var x IntSet
+	for i := 0; i < 1000; i++ {
+		x.Add(i)
+	}
+	fmt.Printf("%.5v\n", &x)
+	x.Clear()
+	x.AddAll(1, 2, 3)
+	fmt.Printf("%.2v %+.2v\n", &x, &x)
+	// Output:
+	// {0 1 2 3 4 ...(995 more)}
+	// {1 2 ...(1 more)} {1 2 3}
+}
+
+func testSets() []*IntSet {
+	var empty, sparse, dense, mixed IntSet
+	sparse.AddAll(0, 1e6, 1e9)
+	for i := 0; i < 10000; i++ {
+		dense.Add(i)
+		if i%7 == 0 {
+			mixed.Add(i * 100)
+		}
+	}
+	mixed.UnionWith(&dense)
+	mixed.Add(1e6)
+	mixed.Remove(1e6) // trailing zero words
+	return []*IntSet{&empty, &sparse, &dense, &mixed}
+}
+
+func TestBinary(t *testing.T) {
+	for _, s := range testSets() {
+		data, err := s.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got IntSet
+		got.AddAll(5, 6, 7) // to be replaced
+		if err := got.UnmarshalBinary(data); err != nil {
+			t.Errorf("UnmarshalBinary(MarshalBinary(%.10v)): %v", s, err)
+			continue
+		}
+		if !got.Equals(s) || !s.Equals(&got) || got.Len() != s.Len() {
+			t.Errorf("UnmarshalBinary(MarshalBinary(%.10v)) = %.10v", s, &got)
+		}
+	}
+
+	// The encoding is compact for both sparse and dense sets.
+	var sparse, dense IntSet
+	sparse.Add(1e9)
+	for i := 0; i < 64000; i++ {
+		dense.Add(i)
+	}
+	for _, test := range []struct {
+		s   *IntSet
+		max int
+	}{
+		{&sparse, 10},
+		{&dense, 8100},
+	} {
+		data, _ := test.s.MarshalBinary()
+		if len(data) > test.max {
+			t.Errorf("%.10v encoded in %d bytes, want at most %d", test.s, len(data), test.max)
+		}
+	}
+
+	for _, data := range [][]byte{
+		nil,
+		{binaryVersion},
+		{2, elemsKind, 0},
+		{binaryVersion, 7},
+		{binaryVersion, elemsKind, 2, 1},       // too few elements
+		{binaryVersion, elemsKind, 1, 0},       // zero delta
+		{binaryVersion, elemsKind, 0, 0},       // trailing data
+		{binaryVersion, wordsKind, 0, 1, 1, 2}, // short word
+		{binaryVersion, wordsKind, 0, 0},       // empty group
+		// Inputs implying huge sets.
+		append(appendUvarint([]byte{binaryVersion, wordsKind}, 1<<56), 1, 0, 0),
+		appendUvarint([]byte{binaryVersion, elemsKind, 1}, 1<<40),
+		appendUvarint([]byte{binaryVersion, elemsKind, 1}, maxDecoded+2),
+		appendUvarint(appendUvarint([]byte{binaryVersion, elemsKind, 2}, maxDecoded+1), 2),
+		append(appendUvarint([]byte{binaryVersion, wordsKind}, maxDecoded/64+1), 1, 0, 0, 0, 0, 0, 0, 0, 0),
+	} {
+		var s IntSet
+		if err := s.UnmarshalBinary(data); err == nil {
+			t.Errorf("UnmarshalBinary(%v) succeeded, want error", data)
+		}
+	}
+}
+
+func TestJSON(t *testing.T) {
+	for _, s := range testSets() {
+		data, err := json.Marshal(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got IntSet
+		if err := json.Unmarshal(data, &got); err != nil {
+			t.Errorf("Unmarshal(Marshal(%.10v)): %v", s, err)
+			continue
+		}
+		if got.String() != s.String() || got.Len() != s.Len() {
+			t.Errorf("Unmarshal(Marshal(%.10v)) = %.10v", s, &got)
+		}
+	}
+
+	var s IntSet
+	s.AddAll(144, 1, 9)
+	if data, _ := json.Marshal(&s); string(data) != "[1,9,144]" {
+		t.Errorf("Marshal(%v) = %s", &s, data)
+	}
+	if data, _ := json.Marshal(new(IntSet)); string(data) != "[]" {
+		t.Errorf("Marshal of empty set = %s", data)
+	}
+	if err := json.Unmarshal([]byte("[1, -2]"), &s); err == nil {
+		t.Errorf("Unmarshal of negative element succeeded")
+	}
+	if err := json.Unmarshal([]byte("[1099511627776]"), &s); err == nil {
+		t.Errorf("Unmarshal of huge element succeeded")
+	}
+}
+
+func TestParse(t *testing.T) {
+	for _, s := range testSets() {
+		got, err := Parse(s.String())
+		if err != nil {
+			t.Errorf("Parse(%.10v.String()): %v", s, err)
+			continue
+		}
+		if got.String() != s.String() {
+			t.Errorf("Parse(%.10v.String()) = %.10v", s, got)
+		}
+	}
+
+	for _, test := range []struct {
+		in, want string
+	}{
+		{"{}", "{}"},
+		{" { 3  1 2 } ", "{1 2 3}"},
+		{"{1-3 2-4 10-10}", "{1 2 3 4 10}"},
+		{"{62-65 127-128}", "{62 63 64 65 127 128}"},
+	} {
+		got, err := Parse(test.in)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", test.in, err)
+		} else if got.String() != test.want {
+			t.Errorf("Parse(%q) = %s, want %s", test.in, got, test.want)
+		}
+	}
+
+	for _, in := range []string{
+		"", "1 2", "{1 2", "{a}", "{-1}", "{1-}", "{3-1}", "{1--2}", "{1,2}",
+		"{0-99999999999}", "{1073741824}",
+	} {
+		if _, err := Parse(in); err == nil {
+			t.Errorf("Parse(%q) succeeded, want error", in)
+		}
+	}
+}
+
+func FuzzUnmarshalBinary(f *testing.F) {
+	for _, s := range testSets() {
+		data, _ := s.MarshalBinary()
+		f.Add(data)
+	}
+	f.Add(append(appendUvarint([]byte{binaryVersion, wordsKind}, 1<<56), 1, 0, 0))
+	f.Add(appendUvarint([]byte{binaryVersion, elemsKind, 1}, 1<<40))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var s IntSet
+		if err := s.UnmarshalBinary(data); err != nil {
+			return
+		}
+		// Whatever decodes must round-trip.
+		again, err := s.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var u IntSet
+		if err := u.UnmarshalBinary(again); err != nil || !u.Equals(&s) || u.Len() != s.Len() {
+			t.Fatalf("round trip of %v failed: %v", data, err)
+		}
+	})
+}
+
+func FuzzParse(f *testing.F) {
+	for _, in := range []string{"{}", "{1 2 3}", "{1-100 200}", "{0-99999999999}", "{18446744073709551615}"} {
+		f.Add(in)
+	}
+	f.Fuzz(func(t *testing.T, in string) {
+		s, err := Parse(in)
+		if err != nil {
+			return
+		}
+		u, err := Parse(s.String())
+		if err != nil || !u.Equals(s) || u.Len() != s.Len() {
+			t.Fatalf("Parse(%q) does not round-trip: %v", in, err)
+		}
+	})
+}
@@ -9,29 +9,43 @@ package intset
 import (
 	"bytes"
 	"fmt"
+	"math"
+	"math/bits"
 )
 
 //!+intset
 
 // An IntSet is a set of small non-negative integers.
 // Its zero value represents the empty set.
+//!-intset
+//
+// Negative values are never members: Add panics if given one,
+// while Has and Remove treat it as absent.
+//!+intset
 type IntSet struct {
 	words []uint64
+	n     int // number of elements
 }
 
 // Has reports whether the set contains the non-negative value x.
 func (s *IntSet) Has(x int) bool {
 	word, bit := x/64, uint(x%64)
-	return word < len(s.words) && s.words[word]&(1<<bit) != 0
+	return x >= 0 && word < len(s.words) && s.words[word]&(1<<bit) != 0
 }
 
 // Add adds the non-negative value x to the set.
 func (s *IntSet) Add(x int) {
+	if x < 0 {
+		panic(fmt.Sprintf("intset: Add of negative value %d", x))
+	}
 	word, bit := x/64, uint(x%64)
 	for word >= len(s.words) {
 		s.words = append(s.words, 0)
 	}
-	s.words[word] |= 1 << bit
+	if s.words[word]&(1<<bit) == 0 {
+		s.words[word] |= 1 << bit
+		s.n++
+	}
 }
 
 // UnionWith sets s to the union of s and t.
@@ -43,6 +57,7 @@ func (s *IntSet) UnionWith(t *IntSet) {
 			s.words = append(s.words, tword)
 		}
 	}
+	s.count()
 }
 
 //!-intset
@@ -72,39 +87,35 @@ func (s *IntSet) String() string {
 
 //!-string
 
-// Len computes the length of the IntSet
-func (s *IntSet) Len() (len int) {
+// Len returns the number of elements in the set.
+func (s *IntSet) Len() int {
+	return s.n
+}
+
+// count recomputes the cached number of elements
+// after an operation on whole words.
+func (s *IntSet) count() {
+	s.n = 0
 	for _, word := range s.words {
-		if word == 0 {
-			continue
-		}
-		for i := 0; i < 64; i++ {
-			if word&(1<<uint(i)) != 0 {
-				len++
-			}
-		}
+		s.n += bits.OnesCount64(word)
 	}
-	return
 }
 
+// Remove removes x from the set, if present.
 func (s *IntSet) Remove(x int) {
-	if s.Len() == 0 {
+	word, bit := x/64, uint(x%64)
+	if x < 0 || word >= len(s.words) || s.words[word]&(1<<bit) == 0 {
 		return
 	}
-
-	word, bit := x/64, uint64(x%64)
-	var mask uint64
-	mask--
-	allOnes := mask
-	mask <<= bit + 1
-	mask |= (allOnes >> (64 - bit))
-	s.words[word] &= mask
+	s.words[word] &^= 1 << bit
+	s.n--
 }
 
 func (s *IntSet) Clear() {
 	for i := range s.words {
 		s.words[i] = 0
 	}
+	s.n = 0
 }
 
 func (s *IntSet) Copy() *IntSet {
@@ -112,16 +123,28 @@ func (s *IntSet) Copy() *IntSet {
 	for _, oldWord := range s.words {
 		newSet.words = append(newSet.words, oldWord)
 	}
+	newSet.n = s.n
 	return &newSet
 }
 
 func (s *IntSet) Equals(another *IntSet) (equals bool) {
-	if len(s.words) != len(another.words) {
+	if s.n != another.n {
 		return
 	}
 
+	// Either set may have trailing zero words, left by Remove or
+	// the in-place operations; treat missing words as zero.
 	for i, word := range s.words {
-		if another.words[i] != word {
+		var anotherWord uint64
+		if i < len(another.words) {
+			anotherWord = another.words[i]
+		}
+		if anotherWord != word {
+			return
+		}
+	}
+	for _, word := range another.words[min(len(s.words), len(another.words)):] {
+		if word != 0 {
 			return
 		}
 	}
@@ -141,6 +164,7 @@ func (s *IntSet) IntersectionWith(another *IntSet) *IntSet {
 	for i := 0; i < len(s.words) && i < len(another.words); i++ {
 		result.words = append(result.words, s.words[i]&another.words[i])
 	}
+	result.count()
 
 	return &result
 }
@@ -155,6 +179,7 @@ func (s *IntSet) DifferenceWith(another *IntSet) *IntSet {
 			result.words = append(result.words, s.words[i])
 		}
 	}
+	result.count()
 	return &result
 }
 
@@ -163,3 +188,132 @@ func (s *IntSet) SymmetricDifference(another *IntSet) *IntSet {
 	result.UnionWith(another.DifferenceWith(s))
 	return result
 }
+
+// IntersectWith sets s to the intersection of s and t.
+func (s *IntSet) IntersectWith(t *IntSet) {
+	if len(s.words) > len(t.words) {
+		s.words = s.words[:len(t.words)]
+	}
+	for i := range s.words {
+		s.words[i] &= t.words[i]
+	}
+	s.count()
+}
+
+// DifferenceWithInPlace sets s to the difference of s and t,
+// the elements of s not in t.
+func (s *IntSet) DifferenceWithInPlace(t *IntSet) {
+	for i := 0; i < len(s.words) && i < len(t.words); i++ {
+		s.words[i] &^= t.words[i]
+	}
+	s.count()
+}
+
+// SymmetricDifferenceWith sets s to the symmetric difference of s
+// and t, the elements in either set but not both.
+func (s *IntSet) SymmetricDifferenceWith(t *IntSet) {
+	for i, tword := range t.words {
+		if i < len(s.words) {
+			s.words[i] ^= tword
+		} else {
+			s.words = append(s.words, tword)
+		}
+	}
+	s.count()
+}
+
+// IsSubset reports whether every element of s is in t.
+func (s *IntSet) IsSubset(t *IntSet) bool {
+	for i, word := range s.words {
+		var tword uint64
+		if i < len(t.words) {
+			tword = t.words[i]
+		}
+		if word&^tword != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// IsDisjoint reports whether s and t have no elements in common.
+func (s *IntSet) IsDisjoint(t *IntSet) bool {
+	for i := 0; i < len(s.words) && i < len(t.words); i++ {
+		if s.words[i]&t.words[i] != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Each calls f for each element of the set in increasing order,
+// until f returns false.
+func (s *IntSet) Each(f func(x int) bool) {
+	for i, word := range s.words {
+		for word != 0 {
+			j := bits.TrailingZeros64(word)
+			if !f(64*i + j) {
+				return
+			}
+			word &= word - 1 // clear lowest set bit
+		}
+	}
+}
+
+// Elems returns the elements of the set in increasing order.
+func (s *IntSet) Elems() []int {
+	var elems []int
+	s.Each(func(x int) bool {
+		elems = append(elems, x)
+		return true
+	})
+	return elems
+}
+
+// Min returns the smallest element of the set.
+// It reports false if the set is empty.
+func (s *IntSet) Min() (int, bool) {
+	return s.Next(-1)
+}
+
+// Max returns the largest element of the set.
+// It reports false if the set is empty.
+func (s *IntSet) Max() (int, bool) {
+	for i := len(s.words) - 1; i >= 0; i-- {
+		if word := s.words[i]; word != 0 {
+			return 64*i + 63 - bits.LeadingZeros64(word), true
+		}
+	}
+	return 0, false
+}
+
+// Next returns the smallest element of the set greater than x,
+// which may be negative.  It reports false if there is none.
+//
+// The elements of s may be visited in increasing order thus:
+//
+//	for x, ok := s.Next(-1); ok; x, ok = s.Next(x) { ... }
+func (s *IntSet) Next(x int) (int, bool) {
+	if x == math.MaxInt {
+		return 0, false
+	}
+	if x < 0 {
+		x = -1
+	}
+	x++
+	i := x / 64
+	if i >= len(s.words) {
+		return 0, false
+	}
+	word := s.words[i] &^ (1<<uint(x%64) - 1) // ignore bits below x
+	for {
+		if word != 0 {
+			return 64*i + bits.TrailingZeros64(word), true
+		}
+		i++
+		if i == len(s.words) {
+			return 0, false
+		}
+		word = s.words[i]
+	}
+}
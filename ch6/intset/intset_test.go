@@ -5,7 +5,12 @@ package intset
 
 import (
 	"fmt"
+	"math"
+	"math/bits"
+	"reflect"
 	"testing"
+
+	"gopl.io/ch2/popcount"
 )
 
 func Example_one() {
@@ -43,13 +48,13 @@ func Example_two() {
 	//!+note
 	fmt.Println(&x)         // "{1 9 42 144}"
 	fmt.Println(x.String()) // "{1 9 42 144}"
-	fmt.Println(x)          // "{[4398046511618 0 65536]}"
+	fmt.Println(x)          // "{[4398046511618 0 65536] 4}"
 	//!-note
 
 	// Output:
 	// {1 9 42 144}
 	// {1 9 42 144}
-	// {[4398046511618 0 65536]}
+	// {[4398046511618 0 65536] 4}
 }
 
 func TestLen(t *testing.T) {
@@ -144,6 +149,14 @@ func TestEquals(t *testing.T) {
 	if x.Equals(&y) {
 		t.Fail()
 	}
+
+	// Trailing zero words do not affect equality.
+	x.Add(200)
+	x.Remove(200)
+	x.Remove(2)
+	if !x.Equals(&y) || !y.Equals(&x) {
+		t.Errorf("%v with trailing zero words not Equals to %v", &x, &y)
+	}
 }
 
 func TestCopy(t *testing.T) {
@@ -210,3 +223,284 @@ func Example_SymmetricDifference() {
 	// {}
 	// {1 3}
 }
+
+func ExampleIntSet_Each() {
+	var x IntSet
+	x.AddAll(1, 9, 42, 144, 1000)
+	x.Each(func(e int) bool {
+		fmt.Println(e)
+		return e < 100
+	})
+
+	// Output:
+	// 1
+	// 9
+	// 42
+	// 144
+}
+
+func ExampleIntSet_Next() {
+	var x IntSet
+	x.AddAll(63, 64, 200)
+	for e, ok := x.Next(-1); ok; e, ok = x.Next(e) {
+		fmt.Println(e)
+	}
+
+	// Output:
+	// 63
+	// 64
+	// 200
+}
+
+func TestInPlace(t *testing.T) {
+	for _, test := range []struct {
+		x, y                 []int
+		inter, diff, symdiff string
+	}{
+		{nil, nil, "{}", "{}", "{}"},
+		{[]int{1, 2, 3}, nil, "{}", "{1 2 3}", "{1 2 3}"},
+		{nil, []int{1, 2, 3}, "{}", "{}", "{1 2 3}"},
+		{[]int{1, 2, 200}, []int{2, 3}, "{2}", "{1 200}", "{1 3 200}"},
+		{[]int{2, 3}, []int{1, 2, 200}, "{2}", "{3}", "{1 3 200}"},
+		{[]int{1, 200}, []int{200}, "{200}", "{1}", "{1}"},
+	} {
+		var x, y IntSet
+		x.AddAll(test.x...)
+		y.AddAll(test.y...)
+		for _, op := range []struct {
+			name string
+			f    func(*IntSet, *IntSet)
+			want string
+		}{
+			{"IntersectWith", (*IntSet).IntersectWith, test.inter},
+			{"DifferenceWithInPlace", (*IntSet).DifferenceWithInPlace, test.diff},
+			{"SymmetricDifferenceWith", (*IntSet).SymmetricDifferenceWith, test.symdiff},
+		} {
+			z := x.Copy()
+			op.f(z, &y)
+			if got := z.String(); got != op.want {
+				t.Errorf("%v.%s(%v) = %s, want %s", &x, op.name, &y, got, op.want)
+			}
+			// The result may keep trailing zero words but must still
+			// equal a set built from its elements.
+			var fresh IntSet
+			fresh.AddAll(z.Elems()...)
+			if !z.Equals(&fresh) || !fresh.Equals(z) {
+				t.Errorf("%v.%s(%v) = %s, not Equals to %s", &x, op.name, &y, z, &fresh)
+			}
+		}
+		// The in-place operations agree with those returning new sets.
+		z := x.Copy()
+		z.IntersectWith(&y)
+		if got, want := z.String(), x.IntersectionWith(&y).String(); got != want {
+			t.Errorf("IntersectWith = %s, IntersectionWith = %s", got, want)
+		}
+	}
+}
+
+func TestSubsetDisjoint(t *testing.T) {
+	for _, test := range []struct {
+		x, y             []int
+		subset, disjoint bool
+	}{
+		{nil, nil, true, true},
+		{nil, []int{1}, true, true},
+		{[]int{1}, nil, false, true},
+		{[]int{1, 100}, []int{1, 2, 100}, true, false},
+		{[]int{1, 2, 100}, []int{1, 100}, false, false},
+		{[]int{1, 200}, []int{2, 100}, false, true},
+	} {
+		var x, y IntSet
+		x.AddAll(test.x...)
+		y.AddAll(test.y...)
+		if got := x.IsSubset(&y); got != test.subset {
+			t.Errorf("%v.IsSubset(%v) = %t", &x, &y, got)
+		}
+		if got := x.IsDisjoint(&y); got != test.disjoint {
+			t.Errorf("%v.IsDisjoint(%v) = %t", &x, &y, got)
+		}
+	}
+}
+
+func TestElemsMinMaxNext(t *testing.T) {
+	var x IntSet
+	if elems := x.Elems(); len(elems) != 0 {
+		t.Errorf("empty Elems() = %v", elems)
+	}
+	if _, ok := x.Min(); ok {
+		t.Errorf("empty Min() reported ok")
+	}
+	if _, ok := x.Max(); ok {
+		t.Errorf("empty Max() reported ok")
+	}
+
+	want := []int{0, 5, 63, 64, 127, 1000}
+	x.AddAll(want...)
+	x.Add(2000)
+	x.Remove(2000) // leave trailing zero words
+	if got := x.Elems(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Elems() = %v, want %v", got, want)
+	}
+	if min, ok := x.Min(); min != 0 || !ok {
+		t.Errorf("Min() = %d, %t", min, ok)
+	}
+	if max, ok := x.Max(); max != 1000 || !ok {
+		t.Errorf("Max() = %d, %t", max, ok)
+	}
+	for _, test := range []struct {
+		x, next int
+		ok      bool
+	}{
+		{-100, 0, true},
+		{-1, 0, true},
+		{0, 5, true},
+		{4, 5, true},
+		{62, 63, true},
+		{63, 64, true},
+		{64, 127, true},
+		{127, 1000, true},
+		{999, 1000, true},
+		{1000, 0, false},
+		{5000, 0, false},
+		{math.MaxInt, 0, false},
+	} {
+		if next, ok := x.Next(test.x); next != test.next || ok != test.ok {
+			t.Errorf("Next(%d) = %d, %t, want %d, %t",
+				test.x, next, ok, test.next, test.ok)
+		}
+	}
+}
+
+func TestNegative(t *testing.T) {
+	var x IntSet
+	x.AddAll(0, 63)
+	for _, v := range []int{-1, -63, -64, -65} {
+		if x.Has(v) {
+			t.Errorf("Has(%d) = true", v)
+		}
+		x.Remove(v) // no-op
+	}
+	if got := x.String(); got != "{0 63}" {
+		t.Errorf("after removing negatives, set = %s", got)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Add(-1) did not panic")
+		}
+	}()
+	x.Add(-1)
+}
+
+func TestLenCached(t *testing.T) {
+	var x, y IntSet
+	x.AddAll(1, 2, 3, 100, 1000)
+	y.AddAll(3, 4, 100, 5000)
+	x.Add(2) // already present
+	for _, test := range []struct {
+		name string
+		set  *IntSet
+	}{
+		{"x", &x},
+		{"Copy", x.Copy()},
+		{"IntersectionWith", x.IntersectionWith(&y)},
+		{"DifferenceWith", x.DifferenceWith(&y)},
+		{"SymmetricDifference", x.SymmetricDifference(&y)},
+	} {
+		if got, want := test.set.Len(), len(test.set.Elems()); got != want {
+			t.Errorf("%s: Len() = %d, want %d", test.name, got, want)
+		}
+	}
+	for _, op := range []func(*IntSet, *IntSet){
+		(*IntSet).UnionWith,
+		(*IntSet).IntersectWith,
+		(*IntSet).DifferenceWithInPlace,
+		(*IntSet).SymmetricDifferenceWith,
+	} {
+		z := x.Copy()
+		op(z, &y)
+		if got, want := z.Len(), len(z.Elems()); got != want {
+			t.Errorf("%v: Len() = %d, want %d", z, got, want)
+		}
+	}
+	x.Clear()
+	if l := x.Len(); l != 0 {
+		t.Errorf("after Clear, Len() = %d", l)
+	}
+}
+
+func TestRemoveOutOfRange(t *testing.T) {
+	var x IntSet
+	x.Remove(1000) // empty set
+	x.AddAll(1, 2)
+	x.Remove(1000) // beyond the last word
+	x.Remove(3)    // absent
+	if got := x.String(); got != "{1 2}" || x.Len() != 2 {
+		t.Errorf("set = %s, Len() = %d, want {1 2}, 2", got, x.Len())
+	}
+}
+
+// lenLoop is the original implementation of Len,
+// which tests each bit of each word.
+func lenLoop(s *IntSet) (len int) {
+	for _, word := range s.words {
+		for i := 0; i < 64; i++ {
+			if word&(1<<uint(i)) != 0 {
+				len++
+			}
+		}
+	}
+	return
+}
+
+func benchmarkLen(b *testing.B, count func(uint64) int) {
+	var s IntSet
+	for i := 0; i < 1e5; i += 3 {
+		s.Add(i)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		n := 0
+		for _, word := range s.words {
+			n += count(word)
+		}
+	}
+}
+
+func BenchmarkLenLoop(b *testing.B) {
+	var s IntSet
+	for i := 0; i < 1e5; i += 3 {
+		s.Add(i)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		lenLoop(&s)
+	}
+}
+
+func BenchmarkLenPopCountTable(b *testing.B) { benchmarkLen(b, popcount.PopCount) }
+func BenchmarkLenOnesCount64(b *testing.B)   { benchmarkLen(b, bits.OnesCount64) }
+
+func BenchmarkLen(b *testing.B) {
+	var s IntSet
+	for i := 0; i < 1e5; i += 3 {
+		s.Add(i)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.Len()
+	}
+}
+
+func BenchmarkRemove(b *testing.B) {
+	var s IntSet
+	for i := 0; i < 1e5; i++ {
+		s.Add(i)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		x := i % 1e5
+		s.Remove(x)
+		s.Add(x)
+	}
+}
@@ -0,0 +1,808 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package intset
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"math/bits"
+	"sort"
+)
+
+// A RoaringSet is a set of non-negative integers with the same API
+// as IntSet, stored compactly whether sparse or dense.
+// Its zero value represents the empty set.
+//
+// Following the Roaring bitmap design, the elements are grouped into
+// chunks by their high bits, and the low 16 bits of the elements in
+// each chunk are held in one of three kinds of container:
+// a sorted array of up to 4096 values, a 65536-bit bitmap, or a
+// sorted list of runs.  Add and Remove choose between arrays and
+// bitmaps; Optimize converts to runs where they are smaller.
+type RoaringSet struct {
+	keys       []int       // high bits of the elements, in increasing order
+	containers []container // low bits of the elements of each chunk, non-empty
+}
+
+const (
+	arrayMax    = 4096         // largest array container; a bitmap is smaller beyond
+	bitmapWords = 1 << 16 / 64 // words in a bitmap container
+)
+
+// split returns the high and low bits of x.
+func split(x int) (hi int, lo uint16) {
+	return x >> 16, uint16(x)
+}
+
+// find returns the index of the chunk with key hi,
+// or of its insertion point, and whether it exists.
+func (s *RoaringSet) find(hi int) (int, bool) {
+	i := sort.SearchInts(s.keys, hi)
+	return i, i < len(s.keys) && s.keys[i] == hi
+}
+
+// insert inserts a chunk with key hi at index i.
+func (s *RoaringSet) insert(i, hi int, c container) {
+	s.keys = append(s.keys, 0)
+	copy(s.keys[i+1:], s.keys[i:])
+	s.keys[i] = hi
+	s.containers = append(s.containers, nil)
+	copy(s.containers[i+1:], s.containers[i:])
+	s.containers[i] = c
+}
+
+// delete deletes the chunk at index i.
+func (s *RoaringSet) delete(i int) {
+	s.keys = append(s.keys[:i], s.keys[i+1:]...)
+	s.containers = append(s.containers[:i], s.containers[i+1:]...)
+}
+
+// Has reports whether the set contains the non-negative value x.
+func (s *RoaringSet) Has(x int) bool {
+	if x < 0 {
+		return false
+	}
+	hi, lo := split(x)
+	i, ok := s.find(hi)
+	return ok && s.containers[i].has(lo)
+}
+
+// Add adds the non-negative value x to the set.
+func (s *RoaringSet) Add(x int) {
+	if x < 0 {
+		panic(fmt.Sprintf("intset: Add of negative value %d", x))
+	}
+	hi, lo := split(x)
+	i, ok := s.find(hi)
+	if !ok {
+		s.insert(i, hi, arrayContainer{lo})
+		return
+	}
+	s.containers[i] = s.containers[i].add(lo)
+}
+
+// AddAll adds the non-negative values to the set.
+func (s *RoaringSet) AddAll(values ...int) {
+	for _, value := range values {
+		s.Add(value)
+	}
+}
+
+// AddRange adds the values from lo up to but not including hi.
+func (s *RoaringSet) AddRange(lo, hi int) {
+	if lo < 0 {
+		panic(fmt.Sprintf("intset: AddRange of negative value %d", lo))
+	}
+	for lo < hi {
+		key, start := split(lo)
+		end := (key + 1) << 16 // end of this chunk
+		if end > hi {
+			end = hi
+		}
+		last := uint16(end - 1)
+		i, ok := s.find(key)
+		switch {
+		case start == 0 && last == 1<<16-1:
+			// The whole chunk is covered.
+			if !ok {
+				s.insert(i, key, runContainer{{0, last}})
+			} else {
+				s.containers[i] = runContainer{{0, last}}
+			}
+		case !ok:
+			s.insert(i, key, runContainer{{start, last}}.mutable())
+		default:
+			b := toBitmap(s.containers[i])
+			b.setRange(start, last)
+			s.containers[i] = b.normalize()
+		}
+		lo = end
+	}
+}
+
+// Remove removes x from the set, if present.
+func (s *RoaringSet) Remove(x int) {
+	if x < 0 {
+		return
+	}
+	hi, lo := split(x)
+	i, ok := s.find(hi)
+	if !ok {
+		return
+	}
+	s.containers[i] = s.containers[i].remove(lo)
+	if s.containers[i].card() == 0 {
+		s.delete(i)
+	}
+}
+
+// Clear removes all elements from the set.
+func (s *RoaringSet) Clear() {
+	s.keys, s.containers = nil, nil
+}
+
+// Copy returns a copy of the set.
+func (s *RoaringSet) Copy() *RoaringSet {
+	t := &RoaringSet{
+		keys:       append([]int(nil), s.keys...),
+		containers: make([]container, len(s.containers)),
+	}
+	for i, c := range s.containers {
+		t.containers[i] = c.clone()
+	}
+	return t
+}
+
+// Len returns the number of elements in the set.
+func (s *RoaringSet) Len() int {
+	n := 0
+	for _, c := range s.containers {
+		n += c.card()
+	}
+	return n
+}
+
+// Equals reports whether s and t contain the same elements.
+func (s *RoaringSet) Equals(t *RoaringSet) bool {
+	if len(s.keys) != len(t.keys) {
+		return false
+	}
+	for i, key := range s.keys {
+		if t.keys[i] != key {
+			return false
+		}
+		c, d := s.containers[i], t.containers[i]
+		if c.card() != d.card() || andCard(c, d) != c.card() {
+			return false
+		}
+	}
+	return true
+}
+
+// UnionWith sets s to the union of s and t.
+func (s *RoaringSet) UnionWith(t *RoaringSet) {
+	for j, key := range t.keys {
+		i, ok := s.find(key)
+		if !ok {
+			s.insert(i, key, t.containers[j].clone())
+		} else {
+			s.containers[i] = union(s.containers[i], t.containers[j])
+		}
+	}
+}
+
+// IntersectWith sets s to the intersection of s and t.
+func (s *RoaringSet) IntersectWith(t *RoaringSet) {
+	for i := 0; i < len(s.keys); {
+		j, ok := t.find(s.keys[i])
+		if ok {
+			s.containers[i] = intersect(s.containers[i], t.containers[j])
+		}
+		if !ok || s.containers[i].card() == 0 {
+			s.delete(i)
+			continue
+		}
+		i++
+	}
+}
+
+// DifferenceWithInPlace sets s to the difference of s and t,
+// the elements of s not in t.
+func (s *RoaringSet) DifferenceWithInPlace(t *RoaringSet) {
+	for i := 0; i < len(s.keys); {
+		if j, ok := t.find(s.keys[i]); ok {
+			s.containers[i] = difference(s.containers[i], t.containers[j])
+			if s.containers[i].card() == 0 {
+				s.delete(i)
+				continue
+			}
+		}
+		i++
+	}
+}
+
+// SymmetricDifferenceWith sets s to the symmetric difference of s
+// and t, the elements in either set but not both.
+func (s *RoaringSet) SymmetricDifferenceWith(t *RoaringSet) {
+	if t == s {
+		s.Clear()
+		return
+	}
+	for j, key := range t.keys {
+		i, ok := s.find(key)
+		if !ok {
+			s.insert(i, key, t.containers[j].clone())
+			continue
+		}
+		s.containers[i] = symmetricDifference(s.containers[i], t.containers[j])
+		if s.containers[i].card() == 0 {
+			s.delete(i)
+		}
+	}
+}
+
+// IntersectionWith returns the intersection of s and t.
+func (s *RoaringSet) IntersectionWith(t *RoaringSet) *RoaringSet {
+	result := s.Copy()
+	result.IntersectWith(t)
+	return result
+}
+
+// DifferenceWith returns the difference of s and t.
+func (s *RoaringSet) DifferenceWith(t *RoaringSet) *RoaringSet {
+	result := s.Copy()
+	result.DifferenceWithInPlace(t)
+	return result
+}
+
+// SymmetricDifference returns the symmetric difference of s and t.
+func (s *RoaringSet) SymmetricDifference(t *RoaringSet) *RoaringSet {
+	result := s.Copy()
+	result.SymmetricDifferenceWith(t)
+	return result
+}
+
+// IsSubset reports whether every element of s is in t.
+func (s *RoaringSet) IsSubset(t *RoaringSet) bool {
+	for i, key := range s.keys {
+		j, ok := t.find(key)
+		if !ok || andCard(s.containers[i], t.containers[j]) != s.containers[i].card() {
+			return false
+		}
+	}
+	return true
+}
+
+// IsDisjoint reports whether s and t have no elements in common.
+func (s *RoaringSet) IsDisjoint(t *RoaringSet) bool {
+	for i, key := range s.keys {
+		if j, ok := t.find(key); ok && andCard(s.containers[i], t.containers[j]) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Each calls f for each element of the set in increasing order,
+// until f returns false.
+func (s *RoaringSet) Each(f func(x int) bool) {
+	for i, key := range s.keys {
+		if !s.containers[i].each(key<<16, f) {
+			return
+		}
+	}
+}
+
+// Elems returns the elements of the set in increasing order.
+func (s *RoaringSet) Elems() []int {
+	var elems []int
+	s.Each(func(x int) bool {
+		elems = append(elems, x)
+		return true
+	})
+	return elems
+}
+
+// Min returns the smallest element of the set.
+// It reports false if the set is empty.
+func (s *RoaringSet) Min() (int, bool) {
+	return s.Next(-1)
+}
+
+// Max returns the largest element of the set.
+// It reports false if the set is empty.
+func (s *RoaringSet) Max() (int, bool) {
+	if len(s.keys) == 0 {
+		return 0, false
+	}
+	i := len(s.keys) - 1
+	return s.keys[i]<<16 | int(s.containers[i].max()), true
+}
+
+// Next returns the smallest element of the set greater than x,
+// which may be negative.  It reports false if there is none.
+func (s *RoaringSet) Next(x int) (int, bool) {
+	if x == math.MaxInt {
+		return 0, false
+	}
+	if x < 0 {
+		x = -1
+	}
+	hi, lo := split(x + 1)
+	i, ok := s.find(hi)
+	if ok {
+		if next, ok := s.containers[i].next(lo); ok {
+			return hi<<16 | int(next), true
+		}
+		i++
+	}
+	if i == len(s.keys) {
+		return 0, false
+	}
+	next, _ := s.containers[i].next(0)
+	return s.keys[i]<<16 | int(next), true
+}
+
+// Optimize converts each container to its most compact kind,
+// using runs where they are smaller than arrays or bitmaps.
+func (s *RoaringSet) Optimize() {
+	for i, c := range s.containers {
+		s.containers[i] = optimize(c)
+	}
+}
+
+// String returns the set as a string of the form "{1 2 3}".
+func (s *RoaringSet) String() string {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	s.Each(func(x int) bool {
+		if buf.Len() > len("{") {
+			buf.WriteByte(' ')
+		}
+		fmt.Fprintf(&buf, "%d", x)
+		return true
+	})
+	buf.WriteByte('}')
+	return buf.String()
+}
+
+// size returns the approximate number of bytes used by the set.
+func (s *RoaringSet) size() int {
+	n := 8 * (cap(s.keys) + 2*cap(s.containers))
+	for _, c := range s.containers {
+		n += c.size()
+	}
+	return n
+}
+
+// A container holds the low 16 bits of the elements of a chunk.
+// Methods that modify a container may return a different one.
+type container interface {
+	has(lo uint16) bool
+	add(lo uint16) container
+	remove(lo uint16) container
+	card() int
+	each(base int, f func(int) bool) bool
+	max() uint16
+	next(lo uint16) (uint16, bool) // smallest element >= lo
+	clone() container
+	size() int // approximate bytes used
+}
+
+// -- array containers --
+
+// An arrayContainer is a sorted array of at most arrayMax values.
+type arrayContainer []uint16
+
+func (a arrayContainer) search(lo uint16) int {
+	return sort.Search(len(a), func(i int) bool { return a[i] >= lo })
+}
+
+func (a arrayContainer) has(lo uint16) bool {
+	i := a.search(lo)
+	return i < len(a) && a[i] == lo
+}
+
+func (a arrayContainer) add(lo uint16) container {
+	i := a.search(lo)
+	if i < len(a) && a[i] == lo {
+		return a
+	}
+	if len(a) == arrayMax {
+		return toBitmap(a).add(lo)
+	}
+	a = append(a, 0)
+	copy(a[i+1:], a[i:])
+	a[i] = lo
+	return a
+}
+
+func (a arrayContainer) remove(lo uint16) container {
+	i := a.search(lo)
+	if i < len(a) && a[i] == lo {
+		a = append(a[:i], a[i+1:]...)
+	}
+	return a
+}
+
+func (a arrayContainer) card() int   { return len(a) }
+func (a arrayContainer) max() uint16 { return a[len(a)-1] }
+func (a arrayContainer) size() int   { return 2 * cap(a) }
+
+func (a arrayContainer) each(base int, f func(int) bool) bool {
+	for _, lo := range a {
+		if !f(base | int(lo)) {
+			return false
+		}
+	}
+	return true
+}
+
+func (a arrayContainer) next(lo uint16) (uint16, bool) {
+	if i := a.search(lo); i < len(a) {
+		return a[i], true
+	}
+	return 0, false
+}
+
+func (a arrayContainer) clone() container {
+	return append(arrayContainer(nil), a...)
+}
+
+// -- bitmap containers --
+
+// A bitmapContainer is a bitmap of all 65536 possible values.
+type bitmapContainer struct {
+	words [bitmapWords]uint64
+	n     int // number of bits set
+}
+
+func (b *bitmapContainer) has(lo uint16) bool {
+	return b.words[lo/64]&(1<<(lo%64)) != 0
+}
+
+func (b *bitmapContainer) add(lo uint16) container {
+	if !b.has(lo) {
+		b.words[lo/64] |= 1 << (lo % 64)
+		b.n++
+	}
+	return b
+}
+
+func (b *bitmapContainer) remove(lo uint16) container {
+	if b.has(lo) {
+		b.words[lo/64] &^= 1 << (lo % 64)
+		b.n--
+	}
+	return b.normalize()
+}
+
+func (b *bitmapContainer) card() int { return b.n }
+func (b *bitmapContainer) size() int { return 8*bitmapWords + 8 }
+
+func (b *bitmapContainer) each(base int, f func(int) bool) bool {
+	for i, word := range b.words {
+		for word != 0 {
+			if !f(base | 64*i + bits.TrailingZeros64(word)) {
+				return false
+			}
+			word &= word - 1 // clear lowest set bit
+		}
+	}
+	return true
+}
+
+func (b *bitmapContainer) max() uint16 {
+	for i := bitmapWords - 1; ; i-- {
+		if word := b.words[i]; word != 0 {
+			return uint16(64*i + 63 - bits.LeadingZeros64(word))
+		}
+	}
+}
+
+func (b *bitmapContainer) next(lo uint16) (uint16, bool) {
+	i := int(lo / 64)
+	word := b.words[i] &^ (1<<(lo%64) - 1) // ignore bits below lo
+	for {
+		if word != 0 {
+			return uint16(64*i + bits.TrailingZeros64(word)), true
+		}
+		i++
+		if i == bitmapWords {
+			return 0, false
+		}
+		word = b.words[i]
+	}
+}
+
+func (b *bitmapContainer) clone() container {
+	c := *b
+	return &c
+}
+
+// setRange sets the bits from start to last inclusive.
+func (b *bitmapContainer) setRange(start, last uint16) {
+	for x := int(start); x <= int(last); x++ {
+		b.words[x/64] |= 1 << uint(x%64)
+	}
+	b.count()
+}
+
+// count recomputes the number of bits set.
+func (b *bitmapContainer) count() {
+	b.n = 0
+	for _, word := range b.words {
+		b.n += bits.OnesCount64(word)
+	}
+}
+
+// normalize returns an array container in place of a bitmap
+// small enough to be held as an array.
+func (b *bitmapContainer) normalize() container {
+	if b.n > arrayMax {
+		return b
+	}
+	a := make(arrayContainer, 0, b.n)
+	b.each(0, func(x int) bool {
+		a = append(a, uint16(x))
+		return true
+	})
+	return a
+}
+
+// toBitmap returns a bitmap holding the values in c,
+// which is c itself if it is a bitmap.
+func toBitmap(c container) *bitmapContainer {
+	if b, ok := c.(*bitmapContainer); ok {
+		return b
+	}
+	b := new(bitmapContainer)
+	c.each(0, func(x int) bool {
+		b.words[x/64] |= 1 << uint(x%64)
+		return true
+	})
+	b.n = c.card()
+	return b
+}
+
+// -- run containers --
+
+// A run is a sequence of consecutive values from start to last inclusive.
+type run struct {
+	start, last uint16
+}
+
+// A runContainer is a sorted list of non-overlapping, non-adjacent runs.
+type runContainer []run
+
+func (r runContainer) search(lo uint16) int {
+	return sort.Search(len(r), func(i int) bool { return r[i].last >= lo })
+}
+
+func (r runContainer) has(lo uint16) bool {
+	i := r.search(lo)
+	return i < len(r) && r[i].start <= lo
+}
+
+// mutable returns the values of r in an array or bitmap container.
+func (r runContainer) mutable() container {
+	if r.card() <= arrayMax {
+		a := make(arrayContainer, 0, r.card())
+		r.each(0, func(x int) bool {
+			a = append(a, uint16(x))
+			return true
+		})
+		return a
+	}
+	b := new(bitmapContainer)
+	for _, run := range r {
+		b.setRange(run.start, run.last)
+	}
+	return b
+}
+
+func (r runContainer) add(lo uint16) container {
+	if r.has(lo) {
+		return r
+	}
+	return r.mutable().add(lo)
+}
+
+func (r runContainer) remove(lo uint16) container {
+	if !r.has(lo) {
+		return r
+	}
+	return r.mutable().remove(lo)
+}
+
+func (r runContainer) card() int {
+	n := 0
+	for _, run := range r {
+		n += int(run.last) - int(run.start) + 1
+	}
+	return n
+}
+
+func (r runContainer) each(base int, f func(int) bool) bool {
+	for _, run := range r {
+		for x := int(run.start); x <= int(run.last); x++ {
+			if !f(base | x) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (r runContainer) max() uint16 { return r[len(r)-1].last }
+func (r runContainer) size() int   { return 4 * cap(r) }
+
+func (r runContainer) next(lo uint16) (uint16, bool) {
+	i := r.search(lo)
+	if i == len(r) {
+		return 0, false
+	}
+	if r[i].start > lo {
+		return r[i].start, true
+	}
+	return lo, true
+}
+
+func (r runContainer) clone() container {
+	return append(runContainer(nil), r...)
+}
+
+// optimize returns the most compact container holding the values in c.
+func optimize(c container) container {
+	var runs runContainer
+	c.each(0, func(x int) bool {
+		if n := len(runs); n > 0 && int(runs[n-1].last)+1 == x {
+			runs[n-1].last = uint16(x)
+		} else {
+			runs = append(runs, run{uint16(x), uint16(x)})
+		}
+		return true
+	})
+	runs = append(runContainer(nil), runs...) // trim capacity
+	if runs.size() < c.size() {
+		return runs
+	}
+	if b, ok := c.(*bitmapContainer); ok {
+		return b.normalize()
+	}
+	return c
+}
+
+// -- binary operations --
+
+// The binary operations below may modify and return their first
+// operand, which must not be shared; they never modify the second.
+
+func union(c, d container) container {
+	if a, ok := c.(arrayContainer); ok {
+		if b, ok := d.(arrayContainer); ok && len(a)+len(b) <= arrayMax {
+			return mergeArrays(a, b, true, true, true)
+		}
+	}
+	b := mutableBitmap(c)
+	other := toBitmap(d)
+	for i := range b.words {
+		b.words[i] |= other.words[i]
+	}
+	b.count()
+	return b.normalize()
+}
+
+func intersect(c, d container) container {
+	if a, ok := c.(arrayContainer); ok {
+		var result arrayContainer
+		for _, lo := range a {
+			if d.has(lo) {
+				result = append(result, lo)
+			}
+		}
+		return result
+	}
+	b := mutableBitmap(c)
+	other := toBitmap(d)
+	for i := range b.words {
+		b.words[i] &= other.words[i]
+	}
+	b.count()
+	return b.normalize()
+}
+
+func difference(c, d container) container {
+	if a, ok := c.(arrayContainer); ok {
+		var result arrayContainer
+		for _, lo := range a {
+			if !d.has(lo) {
+				result = append(result, lo)
+			}
+		}
+		return result
+	}
+	b := mutableBitmap(c)
+	other := toBitmap(d)
+	for i := range b.words {
+		b.words[i] &^= other.words[i]
+	}
+	b.count()
+	return b.normalize()
+}
+
+func symmetricDifference(c, d container) container {
+	if a, ok := c.(arrayContainer); ok {
+		if b, ok := d.(arrayContainer); ok && len(a)+len(b) <= arrayMax {
+			return mergeArrays(a, b, true, false, true)
+		}
+	}
+	b := mutableBitmap(c)
+	other := toBitmap(d)
+	for i := range b.words {
+		b.words[i] ^= other.words[i]
+	}
+	b.count()
+	return b.normalize()
+}
+
+// andCard returns the number of elements common to c and d.
+func andCard(c, d container) int {
+	if a, ok := c.(arrayContainer); ok {
+		n := 0
+		for _, lo := range a {
+			if d.has(lo) {
+				n++
+			}
+		}
+		return n
+	}
+	if _, ok := d.(arrayContainer); ok {
+		return andCard(d, c)
+	}
+	b, other := toBitmap(c), toBitmap(d)
+	n := 0
+	for i := range b.words {
+		n += bits.OnesCount64(b.words[i] & other.words[i])
+	}
+	return n
+}
+
+// mutableBitmap returns a bitmap holding the values in c that may be
+// modified in place of c.
+func mutableBitmap(c container) *bitmapContainer {
+	if r, ok := c.(runContainer); ok {
+		return toBitmap(r.mutable())
+	}
+	return toBitmap(c)
+}
+
+// mergeArrays merges the sorted arrays a and b, keeping the values
+// only in a, in both, and only in b, as specified.
+func mergeArrays(a, b arrayContainer, onlyA, both, onlyB bool) arrayContainer {
+	result := make(arrayContainer, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case j == len(b) || i < len(a) && a[i] < b[j]:
+			if onlyA {
+				result = append(result, a[i])
+			}
+			i++
+		case i == len(a) || b[j] < a[i]:
+			if onlyB {
+				result = append(result, b[j])
+			}
+			j++
+		default:
+			if both {
+				result = append(result, a[i])
+			}
+			i++
+			j++
+		}
+	}
+	return result
+}
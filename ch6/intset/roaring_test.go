@@ -0,0 +1,249 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package intset
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func ExampleRoaringSet() {
+	var x RoaringSet
+	x.AddAll(1, 144, 9, 1e9)
+	fmt.Println(&x)
+	fmt.Println(x.Len(), x.Has(9), x.Has(123))
+
+	// Output:
+	// {1 9 144 1000000000}
+	// 4 true false
+}
+
+// randomSets returns a pair of equal IntSet and RoaringSet values,
+// drawing n elements from [0, max) and adding a random range.
+func randomSets(rng *rand.Rand, n, max int) (*IntSet, *RoaringSet) {
+	var x IntSet
+	var y RoaringSet
+	for i := 0; i < n; i++ {
+		v := rng.Intn(max)
+		x.Add(v)
+		y.Add(v)
+	}
+	lo := rng.Intn(max)
+	hi := lo + rng.Intn(3*1<<16)
+	for v := lo; v < hi; v++ {
+		x.Add(v)
+	}
+	y.AddRange(lo, hi)
+	if rng.Intn(2) == 0 {
+		y.Optimize()
+	}
+	return &x, &y
+}
+
+// check reports an error if the RoaringSet y differs from the IntSet x.
+func check(t *testing.T, what string, x *IntSet, y *RoaringSet) {
+	t.Helper()
+	if xe, ye := x.Elems(), y.Elems(); !reflect.DeepEqual(xe, ye) {
+		t.Fatalf("%s: RoaringSet has %d elements, IntSet %d", what, len(ye), len(xe))
+	}
+	if x.Len() != y.Len() {
+		t.Fatalf("%s: RoaringSet.Len() = %d, IntSet.Len() = %d", what, y.Len(), x.Len())
+	}
+}
+
+func TestRoaringSet(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, params := range []struct{ n, max int }{
+		{0, 1},
+		{10, 100},
+		{1000, 1 << 16},
+		{10000, 1 << 16}, // bitmap containers
+		{20000, 1 << 20},
+	} {
+		for trial := 0; trial < 5; trial++ {
+			x1, y1 := randomSets(rng, params.n, params.max)
+			x2, y2 := randomSets(rng, params.n, params.max)
+			check(t, "Add", x1, y1)
+
+			for _, op := range []struct {
+				name string
+				x    func(*IntSet, *IntSet)
+				y    func(*RoaringSet, *RoaringSet)
+			}{
+				{"UnionWith", (*IntSet).UnionWith, (*RoaringSet).UnionWith},
+				{"IntersectWith", (*IntSet).IntersectWith, (*RoaringSet).IntersectWith},
+				{"DifferenceWithInPlace", (*IntSet).DifferenceWithInPlace, (*RoaringSet).DifferenceWithInPlace},
+				{"SymmetricDifferenceWith", (*IntSet).SymmetricDifferenceWith, (*RoaringSet).SymmetricDifferenceWith},
+			} {
+				x, y := x1.Copy(), y1.Copy()
+				op.x(x, x2)
+				op.y(y, y2)
+				check(t, op.name, x, y)
+				check(t, op.name+" operand", x2, y2)
+				if !y.Equals(y.Copy()) {
+					t.Fatalf("%s: copy not Equal", op.name)
+				}
+
+				x, y = x1.Copy(), y1.Copy()
+				op.x(x, x)
+				op.y(y, y)
+				check(t, op.name+" self", x, y)
+			}
+
+			xi, yi := x1.IntersectionWith(x2), y1.IntersectionWith(y2)
+			if got, want := y1.IsSubset(y2), x1.IsSubset(x2); got != want {
+				t.Errorf("IsSubset = %t, want %t", got, want)
+			}
+			if got, want := yi.IsSubset(y1), true; got != want {
+				t.Errorf("intersection IsSubset = %t, want %t", got, want)
+			}
+			if got, want := y1.IsDisjoint(y2), x1.IsDisjoint(x2); got != want {
+				t.Errorf("IsDisjoint = %t, want %t", got, want)
+			}
+			if got, want := y1.Equals(y2), xi.Len() == x1.Len() && xi.Len() == x2.Len(); got != want {
+				t.Errorf("Equals = %t, want %t", got, want)
+			}
+
+			min1, ok1 := x1.Min()
+			min2, ok2 := y1.Min()
+			max1, _ := x1.Max()
+			max2, _ := y1.Max()
+			if min1 != min2 || ok1 != ok2 || max1 != max2 {
+				t.Errorf("Min, Max = %d, %d, want %d, %d", min2, max2, min1, max1)
+			}
+			for i := 0; i < 100; i++ {
+				v := rng.Intn(params.max+2) - 1
+				n1, ok1 := x1.Next(v)
+				n2, ok2 := y1.Next(v)
+				if n1 != n2 || ok1 != ok2 {
+					t.Errorf("Next(%d) = %d, %t, want %d, %t", v, n2, ok2, n1, ok1)
+				}
+				if x1.Has(v) != y1.Has(v) {
+					t.Errorf("Has(%d) = %t", v, y1.Has(v))
+				}
+			}
+
+			removed := make(map[int]bool)
+			for i := 0; i < params.n; i++ {
+				v := rng.Intn(params.max)
+				removed[v] = true
+				y1.Remove(v)
+			}
+			var x IntSet
+			x1.Each(func(v int) bool {
+				if !removed[v] {
+					x.Add(v)
+				}
+				return true
+			})
+			check(t, "Remove", &x, y1)
+		}
+	}
+}
+
+func TestRoaringSetNextMax(t *testing.T) {
+	var x RoaringSet
+	x.Add(5)
+	if next, ok := x.Next(math.MaxInt); ok {
+		t.Errorf("Next(MaxInt) = %d, true, want false", next)
+	}
+}
+
+func TestRoaringSetSize(t *testing.T) {
+	var sparse RoaringSet
+	sparse.Add(1e9)
+	if size := sparse.size(); size > 100 {
+		t.Errorf("set {1e9} uses %d bytes", size)
+	}
+
+	var dense RoaringSet
+	dense.AddRange(0, 1e7)
+	dense.Optimize()
+	if size := dense.size(); size > 1e4 { // vs 1.25MB for an IntSet
+		t.Errorf("set [0, 1e7) uses %d bytes after Optimize", size)
+	}
+	if n := dense.Len(); n != 1e7 {
+		t.Errorf("set [0, 1e7) has Len %d", n)
+	}
+	dense.Remove(12345)
+	if dense.Has(12345) || !dense.Has(12346) || dense.Len() != 1e7-1 {
+		t.Errorf("Remove from run container failed")
+	}
+}
+
+// Benchmarks comparing IntSet and RoaringSet.
+
+func benchmarkAdd(b *testing.B, n, max int, newSet func() interface{ Add(int) }) {
+	rng := rand.New(rand.NewSource(1))
+	values := make([]int, n)
+	for i := range values {
+		values[i] = rng.Intn(max)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s := newSet()
+		for _, v := range values {
+			s.Add(v)
+		}
+	}
+}
+
+func newIntSet() interface{ Add(int) }     { return new(IntSet) }
+func newRoaringSet() interface{ Add(int) } { return new(RoaringSet) }
+
+func BenchmarkIntSetAddDense(b *testing.B)      { benchmarkAdd(b, 1e5, 1e5, newIntSet) }
+func BenchmarkRoaringSetAddDense(b *testing.B)  { benchmarkAdd(b, 1e5, 1e5, newRoaringSet) }
+func BenchmarkIntSetAddSparse(b *testing.B)     { benchmarkAdd(b, 1e3, 1e9, newIntSet) }
+func BenchmarkRoaringSetAddSparse(b *testing.B) { benchmarkAdd(b, 1e3, 1e9, newRoaringSet) }
+
+func benchmarkHas(b *testing.B, has func(int) bool) {
+	for i := 0; i < b.N; i++ {
+		has(i % 1e6)
+	}
+}
+
+func BenchmarkIntSetHas(b *testing.B) {
+	var s IntSet
+	for i := 0; i < 1e6; i += 3 {
+		s.Add(i)
+	}
+	b.ResetTimer()
+	benchmarkHas(b, s.Has)
+}
+
+func BenchmarkRoaringSetHas(b *testing.B) {
+	var s RoaringSet
+	for i := 0; i < 1e6; i += 3 {
+		s.Add(i)
+	}
+	b.ResetTimer()
+	benchmarkHas(b, s.Has)
+}
+
+func BenchmarkIntSetUnionWith(b *testing.B) {
+	var x, y IntSet
+	for i := 0; i < 1e6; i += 3 {
+		x.Add(i)
+		y.Add(i + 1)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		x.Copy().UnionWith(&y)
+	}
+}
+
+func BenchmarkRoaringSetUnionWith(b *testing.B) {
+	var x, y RoaringSet
+	for i := 0; i < 1e6; i += 3 {
+		x.Add(i)
+		y.Add(i + 1)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		x.Copy().UnionWith(&y)
+	}
+}
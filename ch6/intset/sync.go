@@ -0,0 +1,152 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package intset
+
+import (
+	"fmt"
+	"math/bits"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// A SyncIntSet is a set of small non-negative integers that is safe
+// for concurrent use by multiple goroutines, such as a set of items
+// already seen by a crawler.  Its zero value represents the empty set.
+//
+// Has never blocks, and Add blocks only while the bit vector grows.
+// Each word is updated by compare-and-swap, and the bit vector is
+// grown by copying it and atomically swapping in the copy.
+// Elements cannot be removed.
+type SyncIntSet struct {
+	words atomic.Value // *syncWords, nil until the first Add
+	mu    sync.Mutex   // serializes growth
+}
+
+// syncWords is an immutable-length bit vector whose words
+// are accessed atomically.
+//
+// Before copying a vector, grow freezes it and waits until no Add
+// is writing to it.  An Add announces itself in writers before
+// checking frozen, so it either sees the vector frozen and retries
+// on its replacement, or finishes its write before the copy.
+type syncWords struct {
+	w       []uint64
+	writers int32 // number of Adds writing to w
+	frozen  int32 // non-zero once grow has begun to copy w
+}
+
+func (s *SyncIntSet) load() *syncWords {
+	p, _ := s.words.Load().(*syncWords)
+	return p
+}
+
+// Has reports whether the set contains the non-negative value x.
+func (s *SyncIntSet) Has(x int) bool {
+	word, bit := x/64, uint(x%64)
+	p := s.load()
+	return x >= 0 && p != nil && word < len(p.w) &&
+		atomic.LoadUint64(&p.w[word])&(1<<bit) != 0
+}
+
+// Add adds the non-negative value x to the set, and reports whether
+// it was newly added.  Of several concurrent calls to Add with the
+// same value, exactly one reports true, so Add can serve as an
+// atomic test-and-set.  Once Add returns, Has(x) reports true.
+func (s *SyncIntSet) Add(x int) bool {
+	if x < 0 {
+		panic(fmt.Sprintf("intset: Add of negative value %d", x))
+	}
+	word, bit := x/64, uint(x%64)
+	for {
+		p := s.load()
+		if p == nil || word >= len(p.w) {
+			s.grow(word + 1)
+			continue
+		}
+		atomic.AddInt32(&p.writers, 1)
+		if atomic.LoadInt32(&p.frozen) != 0 {
+			// A grow is copying p; wait for it to finish.
+			atomic.AddInt32(&p.writers, -1)
+			s.mu.Lock()
+			s.mu.Unlock()
+			continue
+		}
+		added := setBits(&p.w[word], 1<<bit)
+		atomic.AddInt32(&p.writers, -1)
+		return added
+	}
+}
+
+// setBits atomically sets the bits of mask in *addr, and reports
+// whether any of them was previously clear.
+func setBits(addr *uint64, mask uint64) bool {
+	for {
+		old := atomic.LoadUint64(addr)
+		if old&mask == mask {
+			return false
+		}
+		if atomic.CompareAndSwapUint64(addr, old, old|mask) {
+			return true
+		}
+	}
+}
+
+// grow replaces the bit vector by one of at least n words.
+func (s *SyncIntSet) grow(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old := s.load()
+	var oldw []uint64
+	if old != nil {
+		oldw = old.w
+		if n <= len(oldw) {
+			return // another goroutine grew it
+		}
+		atomic.StoreInt32(&old.frozen, 1)
+		for atomic.LoadInt32(&old.writers) != 0 {
+			runtime.Gosched()
+		}
+	}
+	if n < 2*len(oldw) {
+		n = 2 * len(oldw)
+	}
+	p := &syncWords{w: make([]uint64, n)}
+	for i := range oldw {
+		p.w[i] = atomic.LoadUint64(&oldw[i])
+	}
+	s.words.Store(p)
+}
+
+// Len returns the number of elements in the set.  If there are
+// concurrent calls to Add, the result may not reflect all of them.
+func (s *SyncIntSet) Len() int {
+	n := 0
+	if p := s.load(); p != nil {
+		for i := range p.w {
+			n += bits.OnesCount64(atomic.LoadUint64(&p.w[i]))
+		}
+	}
+	return n
+}
+
+// Snapshot returns an IntSet containing the elements of the set.
+// If there are concurrent calls to Add, the result may not reflect
+// all of them.
+func (s *SyncIntSet) Snapshot() *IntSet {
+	var t IntSet
+	if p := s.load(); p != nil {
+		t.words = make([]uint64, len(p.w))
+		for i := range p.w {
+			t.words[i] = atomic.LoadUint64(&p.w[i])
+		}
+	}
+	t.count()
+	return &t
+}
+
+// String returns the set as a string of the form "{1 2 3}".
+func (s *SyncIntSet) String() string {
+	return s.Snapshot().String()
+}
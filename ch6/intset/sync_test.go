@@ -0,0 +1,176 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package intset
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestSyncIntSet(t *testing.T) {
+	var s SyncIntSet
+	if s.Has(0) || s.Len() != 0 || s.String() != "{}" {
+		t.Fatalf("zero SyncIntSet is not empty: %s", &s)
+	}
+
+	// Each goroutine adds every value congruent to its index,
+	// in increasing order, so the set grows while others add.
+	const goroutines, n = 8, 100000
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for x := g; x < n; x += goroutines / 2 {
+				s.Add(x)
+				if !s.Has(x) {
+					t.Errorf("Has(%d) = false after Add", x)
+					return
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	if got := s.Len(); got != n {
+		t.Errorf("Len() = %d, want %d", got, n)
+	}
+	for x := 0; x < n; x++ {
+		if !s.Has(x) {
+			t.Fatalf("Has(%d) = false", x)
+		}
+	}
+	if s.Has(-1) || s.Has(n) {
+		t.Errorf("Has reports values never added")
+	}
+	if snap := s.Snapshot(); snap.Len() != n {
+		t.Errorf("Snapshot().Len() = %d, want %d", snap.Len(), n)
+	}
+}
+
+func TestSyncIntSetConcurrentReads(t *testing.T) {
+	var s SyncIntSet
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for x := 0; x < 50000; x += 3 {
+			s.Add(x)
+		}
+	}()
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				for x := 1; x < 50000; x += 3 {
+					if s.Has(x) {
+						t.Errorf("Has(%d) = true", x)
+						return
+					}
+				}
+				s.Len()
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestSyncIntSetAddOnce(t *testing.T) {
+	// Goroutines race to add the same values, in increasing
+	// order so that the set grows meanwhile; exactly one Add
+	// of each value reports that it was newly added.
+	var s SyncIntSet
+	const goroutines, n = 8, 50000
+	var added [goroutines][]int
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for x := 0; x < n; x++ {
+				if s.Add(x) {
+					added[g] = append(added[g], x)
+				}
+				if !s.Has(x) {
+					t.Errorf("Has(%d) = false after Add", x)
+					return
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	var all IntSet
+	total := 0
+	for _, xs := range added {
+		all.AddAll(xs...)
+		total += len(xs)
+	}
+	if total != n || all.Len() != n {
+		t.Errorf("Add reported %d new values (%d distinct), want %d", total, all.Len(), n)
+	}
+	if s.Add(0) {
+		t.Errorf("Add(0) of existing value reported true")
+	}
+}
+
+// A mutexIntSet is an IntSet guarded by a mutex,
+// for comparison with SyncIntSet.
+type mutexIntSet struct {
+	mu sync.RWMutex
+	s  IntSet
+}
+
+func (m *mutexIntSet) Add(x int) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.s.Has(x) {
+		return false
+	}
+	m.s.Add(x)
+	return true
+}
+
+func (m *mutexIntSet) Has(x int) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.s.Has(x)
+}
+
+type concurrentSet interface {
+	Add(int) bool
+	Has(int) bool
+}
+
+// benchmarkConcurrent measures a workload of one Add per
+// readsPerAdd calls to Has, from parallel goroutines.
+func benchmarkConcurrent(b *testing.B, s concurrentSet, readsPerAdd int) {
+	const max = 1 << 20
+	for x := 0; x < max; x += 2 {
+		s.Add(x)
+	}
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		x := 0
+		for pb.Next() {
+			x = (x + 7919) % max
+			if x%readsPerAdd == 0 {
+				s.Add(x)
+			} else {
+				s.Has(x)
+			}
+		}
+	})
+}
+
+func BenchmarkSyncIntSetRead(b *testing.B)   { benchmarkConcurrent(b, new(SyncIntSet), 100) }
+func BenchmarkMutexIntSetRead(b *testing.B)  { benchmarkConcurrent(b, new(mutexIntSet), 100) }
+func BenchmarkSyncIntSetWrite(b *testing.B)  { benchmarkConcurrent(b, new(SyncIntSet), 2) }
+func BenchmarkMutexIntSetWrite(b *testing.B) { benchmarkConcurrent(b, new(mutexIntSet), 2) }
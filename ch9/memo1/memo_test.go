@@ -14,13 +14,13 @@ var httpGetBody = memotest.HTTPGetBody
 
 func Test(t *testing.T) {
 	m := memo.New(httpGetBody)
-	memotest.Sequential(t, m)
+	memotest.Sequential(t, m, memotest.AnyBody)
 }
 
 // NOTE: not concurrency-safe!  Test fails.
 func TestConcurrent(t *testing.T) {
 	m := memo.New(httpGetBody)
-	memotest.Concurrent(t, m)
+	memotest.Concurrent(t, m, memotest.AnyBody)
 }
 
 /*
@@ -14,10 +14,14 @@ var httpGetBody = memotest.HTTPGetBody
 
 func Test(t *testing.T) {
 	m := memo.New(httpGetBody)
-	memotest.Sequential(t, m)
+	memotest.Sequential(t, m, memotest.AnyBody)
 }
 
 func TestConcurrent(t *testing.T) {
 	m := memo.New(httpGetBody)
-	memotest.Concurrent(t, m)
+	memotest.Concurrent(t, m, memotest.AnyBody)
+}
+
+func BenchmarkLatency(b *testing.B) {
+	memotest.Benchmark(b, memo.New(httpGetBody))
 }
@@ -12,41 +12,47 @@ package memo
 import "sync"
 
 // Func is the type of the function to memoize.
-type Func func(string) (interface{}, error)
+type Func[K comparable, V any] func(K) (V, error)
 
-type result struct {
-	value interface{}
+type result[V any] struct {
+	value V
 	err   error
 }
 
 //!+
-type entry struct {
-	res   result
+type entry[V any] struct {
+	res   result[V]
 	ready chan struct{} // closed when res is ready
 }
 
-func New(f Func) *Memo {
-	return &Memo{f: f, cache: make(map[string]*entry)}
+func New[K comparable, V any](f Func[K, V]) *Memo[K, V] {
+	return &Memo[K, V]{f: f, cache: make(map[K]*entry[V])}
 }
 
-type Memo struct {
-	f     Func
+type Memo[K comparable, V any] struct {
+	f     Func[K, V]
 	mu    sync.Mutex // guards cache
-	cache map[string]*entry
+	cache map[K]*entry[V]
 }
 
-func (memo *Memo) Get(key string) (value interface{}, err error) {
+func (memo *Memo[K, V]) Get(key K) (value V, err error) {
+	return memo.get(key, func() (V, error) { return memo.f(key) })
+}
+
+// get returns the result for key, calling compute if this
+// is the first request for it.
+func (memo *Memo[K, V]) get(key K, compute func() (V, error)) (V, error) {
 	memo.mu.Lock()
 	e := memo.cache[key]
 	if e == nil {
 		// This is the first request for this key.
 		// This goroutine becomes responsible for computing
 		// the value and broadcasting the ready condition.
-		e = &entry{ready: make(chan struct{})}
+		e = &entry[V]{ready: make(chan struct{})}
 		memo.cache[key] = e
 		memo.mu.Unlock()
 
-		e.res.value, e.res.err = memo.f(key)
+		e.res.value, e.res.err = compute()
 
 		close(e.ready) // broadcast ready condition
 	} else {
@@ -59,3 +65,22 @@ func (memo *Memo) Get(key string) (value interface{}, err error) {
 }
 
 //!-
+
+// A KeyedMemo memoizes a function whose keys need not be comparable,
+// such as slices, or whose equality should differ from ==, such as
+// case-insensitive strings.  A key function maps each key to a
+// comparable value, and keys with the same value share a result.
+type KeyedMemo[K any, H comparable, V any] struct {
+	f    func(K) (V, error)
+	key  func(K) H
+	memo *Memo[H, V]
+}
+
+// NewWithKey returns a memoization of f that identifies keys by key.
+func NewWithKey[K any, H comparable, V any](f func(K) (V, error), key func(K) H) *KeyedMemo[K, H, V] {
+	return &KeyedMemo[K, H, V]{f: f, key: key, memo: New[H, V](nil)}
+}
+
+func (memo *KeyedMemo[K, H, V]) Get(key K) (V, error) {
+	return memo.memo.get(memo.key(key), func() (V, error) { return memo.f(key) })
+}
@@ -4,20 +4,91 @@
 package memo_test
 
 import (
+	"fmt"
+	"sync"
 	"testing"
 
 	"gopl.io/ch9/memo4"
 	"gopl.io/ch9/memotest"
 )
 
-var httpGetBody = memotest.HTTPGetBody
+var httpGetBody = memotest.HTTPGetBodyBytes
 
 func Test(t *testing.T) {
 	m := memo.New(httpGetBody)
-	memotest.Sequential(t, m)
+	memotest.Sequential(t, m, memotest.Bytes)
 }
 
 func TestConcurrent(t *testing.T) {
 	m := memo.New(httpGetBody)
-	memotest.Concurrent(t, m)
+	memotest.Concurrent(t, m, memotest.Bytes)
+}
+
+func TestTypedKey(t *testing.T) {
+	type point struct{ x, y int }
+	var mu sync.Mutex
+	calls := 0
+	m := memo.New(func(p point) (int, error) {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+		return p.x * p.y, nil
+	})
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if v, err := m.Get(point{3, 4}); v != 12 || err != nil {
+				t.Errorf("Get = %d, %v, want 12, nil", v, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if calls != 1 {
+		t.Errorf("computed %d times, want 1", calls)
+	}
+}
+
+func BenchmarkLatency(b *testing.B) {
+	memotest.Benchmark(b, memo.New(httpGetBody))
+}
+
+func TestConcurrentOnce(t *testing.T) {
+	m := memo.New(httpGetBody)
+	memotest.ConcurrentOnce(t, m, memotest.Bytes)
+}
+
+func TestKeyed(t *testing.T) {
+	var mu sync.Mutex
+	calls := 0
+	sum := func(xs []int) (int, error) {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+		total := 0
+		for _, x := range xs {
+			total += x
+		}
+		return total, nil
+	}
+	// Slices are not comparable, so key them by their printed form.
+	m := memo.NewWithKey(sum, func(xs []int) string { return fmt.Sprint(xs) })
+	for _, test := range []struct {
+		xs   []int
+		want int
+	}{
+		{[]int{1, 2, 3}, 6},
+		{[]int{1, 2, 3}, 6},
+		{[]int{4}, 4},
+		{[]int{1, 2, 3}, 6},
+	} {
+		xs, want := test.xs, test.want
+		if got, err := m.Get(xs); got != want || err != nil {
+			t.Errorf("Get(%v) = %d, %v, want %d", xs, got, err, want)
+		}
+	}
+	if calls != 2 {
+		t.Errorf("computed %d times, want 2", calls)
+	}
 }
@@ -7,82 +7,517 @@
 // of a function.  Requests for different keys proceed in parallel.
 // Concurrent requests for the same key block until the first completes.
 // This implementation uses a monitor goroutine.
+//
+// By default results are cached forever.  NewWithOptions bounds the
+// cache by age and by number of entries.  NewWithStore adds a Store,
+// such as a FileStore, that keeps results beyond the cache.  NewWithKey
+// memoizes functions of keys that are not comparable with ==.
+//
+// A client may abandon a request by cancelling its context.  The
+// computation continues while any client still waits for it; once
+// all have abandoned it, its context is cancelled and it is removed
+// from the cache, so a later request starts afresh.
 package memo
 
+import (
+	"container/list"
+	"context"
+	"time"
+)
+
 //!+Func
 
-// Func is the type of the function to memoize.
-type Func func(key string) (interface{}, error)
+// Func is the type of the function to memoize.  The context is
+// cancelled if every client waiting for the result abandons it.
+type Func[K comparable, V any] func(ctx context.Context, key K) (V, error)
 
 // A result is the result of calling a Func.
-type result struct {
-	value interface{}
+type result[V any] struct {
+	value V
 	err   error
 }
 
-type entry struct {
-	res   result
-	ready chan struct{} // closed when res is ready
+// An entry is a cached or in-progress computation.
+// All its fields except res are owned by the monitor goroutine;
+// res is written by the computation before it reports done.
+type entry[K comparable, V any] struct {
+	res     result[V]
+	key     K
+	f       func(context.Context) (V, error) // if not nil, used in place of the Func
+	cancel  context.CancelFunc
+	waiters []chan<- result[V] // clients awaiting res
+	elem    *list.Element      // position in LRU list; nil if not cached
+	done    bool               // res is ready
+	expires time.Time          // when res becomes stale; zero if never
 }
 
 //!-Func
 
+// An ErrorPolicy says how a Memo caches a computation that fails.
+type ErrorPolicy int
+
+const (
+	CacheErrors  ErrorPolicy = iota // cache errors like values
+	ExpireErrors                    // cache errors for Options.ErrorTTL
+	ForgetErrors                    // never cache errors
+)
+
+// Options bound the size and age of the cache, and say how it treats
+// errors.  The zero value imposes no bounds and caches errors like
+// values.
+type Options struct {
+	// TTL is how long a result is cached after it is computed.
+	// If zero, results never expire.
+	TTL time.Duration
+
+	// MaxEntries is the number of results to cache before the
+	// least recently used is evicted.  Computations in progress
+	// are never evicted, so the cache may briefly exceed this size.
+	// If zero, the cache is unbounded.
+	MaxEntries int
+
+	// Errors says whether failed computations are cached, and
+	// ErrorTTL how long for if Errors is ExpireErrors.  Clients
+	// already waiting for a failed computation receive its error
+	// whatever the policy.
+	Errors   ErrorPolicy
+	ErrorTTL time.Duration
+
+	// Retries is how many times a failed computation is retried
+	// before its error is reported.  It waits Backoff before the
+	// first retry and doubles the wait each time, like
+	// WaitForServer in gopl.io/ch5/wait.  All clients waiting for
+	// the key share the retries.  A computation whose context is
+	// cancelled is not retried.
+	Retries int
+	Backoff time.Duration
+}
+
+// Stats reports counts of cache events since the Memo was created.
+type Stats struct {
+	Hits      int // requests satisfied by a cached or in-progress result
+	Misses    int // requests that started a computation
+	Evictions int // results removed for expiry or to respect MaxEntries
+	InFlight  int // computations currently in progress
+}
+
 //!+get
 
-// A request is a message requesting that the Func be applied to key.
-type request struct {
-	key      string
-	response chan<- result // the client wants a single result
+type op int
+
+const (
+	get        op = iota // apply the Func to key
+	invalidate           // remove key from the cache
+	stats                // report statistics on response
+)
+
+// A request is a message to the monitor goroutine.
+type request[K comparable, V any] struct {
+	op       op
+	key      K
+	f        func(context.Context) (V, error) // if not nil, used in place of the Func
+	response chan<- result[V]                 // the client wants a single result
+	stats    chan<- Stats
 }
 
-type Memo struct{ requests chan request }
+type Memo[K comparable, V any] struct {
+	f        Func[K, V]
+	opts     Options
+	store    Store[K, V] // may be nil
+	requests chan request[K, V]
+	done     chan *entry[K, V] // entries whose computation has finished
+
+	// Clients that give up send their response channel on abandons,
+	// which unlike requests stays open after Close until the monitor
+	// goroutine closes exited.
+	abandons chan chan<- result[V]
+	exited   chan struct{}
+}
 
 // New returns a memoization of f.  Clients must subsequently call Close.
-func New(f Func) *Memo {
-	memo := &Memo{requests: make(chan request)}
-	go memo.server(f)
+func New[K comparable, V any](f Func[K, V]) *Memo[K, V] {
+	return NewWithOptions(f, Options{})
+}
+
+// NewWithOptions returns a memoization of f whose cache is bounded
+// by opts.  Clients must subsequently call Close.
+func NewWithOptions[K comparable, V any](f Func[K, V], opts Options) *Memo[K, V] {
+	return NewWithStore(f, opts, nil)
+}
+
+// NewWithStore returns a memoization of f whose cache is bounded by
+// opts and backed by store, if not nil.  Clients must subsequently
+// call Close, and then close the store if necessary.
+func NewWithStore[K comparable, V any](f Func[K, V], opts Options, store Store[K, V]) *Memo[K, V] {
+	memo := &Memo[K, V]{
+		f:        f,
+		opts:     opts,
+		store:    store,
+		requests: make(chan request[K, V]),
+		done:     make(chan *entry[K, V]),
+		abandons: make(chan chan<- result[V]),
+		exited:   make(chan struct{}),
+	}
+	go memo.server()
 	return memo
 }
 
-func (memo *Memo) Get(key string) (interface{}, error) {
-	response := make(chan result)
-	memo.requests <- request{key, response}
-	res := <-response
-	return res.value, res.err
+// Get returns the result of applying the Func to key, computing it
+// if necessary.  If ctx is cancelled first, Get returns ctx.Err().
+func (memo *Memo[K, V]) Get(ctx context.Context, key K) (V, error) {
+	return memo.get(ctx, key, nil)
+}
+
+// get is like Get, but computes the result with f, if not nil,
+// instead of the Func.
+func (memo *Memo[K, V]) get(ctx context.Context, key K, f func(context.Context) (V, error)) (V, error) {
+	var zero V
+	if err := ctx.Err(); err != nil {
+		return zero, err
+	}
+	// The response channel is buffered so that the monitor
+	// never blocks on a client that has gone away.
+	response := make(chan result[V], 1)
+	memo.requests <- request[K, V]{op: get, key: key, f: f, response: response}
+	select {
+	case res := <-response:
+		return res.value, res.err
+	case <-ctx.Done():
+		select {
+		case memo.abandons <- response:
+		case <-memo.exited:
+			// The monitor delivered our result and then
+			// stopped after Close; there is nothing to abandon.
+		}
+		return zero, ctx.Err()
+	}
+}
+
+// Invalidate removes any cached or stored result for key, so that
+// the next request recomputes it.  Clients already waiting for a
+// computation in progress still receive its result, which may
+// still be stored.  It returns any error from the store.
+func (memo *Memo[K, V]) Invalidate(key K) error {
+	// Delete from the store first, so that a request that
+	// misses the cache cannot reload the stale result.
+	var err error
+	if memo.store != nil {
+		err = memo.store.Delete(key)
+	}
+	memo.requests <- request[K, V]{op: invalidate, key: key}
+	return err
+}
+
+// Stats returns the memo's statistics.
+func (memo *Memo[K, V]) Stats() Stats {
+	response := make(chan Stats)
+	memo.requests <- request[K, V]{op: stats, stats: response}
+	return <-response
 }
 
-func (memo *Memo) Close() { close(memo.requests) }
+// Close stops the monitor goroutine once all computations in
+// progress have finished.  Clients already waiting in Get may still
+// receive their results or abandon them, but no new requests may be
+// made after Close.
+func (memo *Memo[K, V]) Close() { close(memo.requests) }
 
 //!-get
 
 //!+monitor
 
-func (memo *Memo) server(f Func) {
-	cache := make(map[string]*entry)
-	for req := range memo.requests {
-		e := cache[req.key]
-		if e == nil {
-			// This is the first request for this key.
-			e = &entry{ready: make(chan struct{})}
-			cache[req.key] = e
-			go e.call(f, req.key) // call f(key)
+// A cache holds the state of the monitor goroutine.
+type cache[K comparable, V any] struct {
+	opts    Options
+	entries map[K]*entry[K, V]
+	lru     *list.List // of *entry, most recently used first
+	waiting map[chan<- result[V]]*entry[K, V]
+	stats   Stats
+}
+
+func (memo *Memo[K, V]) server() {
+	defer close(memo.exited)
+	opts := memo.opts
+	c := &cache[K, V]{
+		opts:    opts,
+		entries: make(map[K]*entry[K, V]),
+		lru:     list.New(),
+		waiting: make(map[chan<- result[V]]*entry[K, V]),
+	}
+
+	// Sweep expired entries periodically, so that keys
+	// that are never requested again don't accumulate.
+	var sweep <-chan time.Time
+	if period := opts.sweepPeriod(); period > 0 {
+		ticker := time.NewTicker(period)
+		defer ticker.Stop()
+		sweep = ticker.C
+	}
+
+	// After Close, keep receiving until every computation has
+	// reported that it is done, so that none of them leaks.
+	requests := memo.requests
+	for requests != nil || c.stats.InFlight > 0 {
+		select {
+		case req, ok := <-requests:
+			if !ok {
+				requests = nil
+				continue
+			}
+			switch req.op {
+			case get:
+				e := c.lookup(req.key)
+				if e == nil {
+					// This is the first request for this key.
+					e = c.add(req.key)
+					e.f = req.f
+					var ctx context.Context
+					ctx, e.cancel = context.WithCancel(context.Background())
+					go memo.call(ctx, e) // call f(ctx, key)
+				}
+				c.deliver(e, req.response)
+			case invalidate:
+				if e := c.entries[req.key]; e != nil {
+					c.remove(e)
+				}
+			case stats:
+				req.stats <- c.stats
+			}
+
+		case response := <-memo.abandons:
+			c.abandon(response)
+
+		case e := <-memo.done:
+			c.stats.InFlight--
+			e.done = true
+			e.cancel() // release the context's resources
+			for _, response := range e.waiters {
+				c.deliver(e, response)
+			}
+			e.waiters = nil
+			c.expire(e)
+			c.evict()
+
+		case now := <-sweep:
+			for _, e := range c.entries {
+				if c.expired(e, now) {
+					c.remove(e)
+					c.stats.Evictions++
+				}
+			}
+		}
+	}
+}
+
+// lookup returns the entry for key, or nil if there is none or it
+// has expired, and records a hit or miss.
+func (c *cache[K, V]) lookup(key K) *entry[K, V] {
+	e := c.entries[key]
+	if e != nil && c.expired(e, time.Now()) {
+		c.remove(e)
+		c.stats.Evictions++
+		e = nil
+	}
+	if e == nil {
+		c.stats.Misses++
+		return nil
+	}
+	c.stats.Hits++
+	c.lru.MoveToFront(e.elem)
+	return e
+}
+
+// add creates an in-progress entry for key.
+func (c *cache[K, V]) add(key K) *entry[K, V] {
+	e := &entry[K, V]{key: key}
+	e.elem = c.lru.PushFront(e)
+	c.entries[key] = e
+	c.stats.InFlight++
+	c.evict()
+	return e
+}
+
+// remove removes e from the cache, if it is still there.
+func (c *cache[K, V]) remove(e *entry[K, V]) {
+	if e.elem == nil {
+		return
+	}
+	c.lru.Remove(e.elem)
+	e.elem = nil
+	delete(c.entries, e.key)
+}
+
+// deliver sends e's result to the client if it is ready,
+// or records that the client is waiting for it.
+func (c *cache[K, V]) deliver(e *entry[K, V], response chan<- result[V]) {
+	if e.done {
+		delete(c.waiting, response)
+		response <- e.res // never blocks; see Get
+		return
+	}
+	e.waiters = append(e.waiters, response)
+	c.waiting[response] = e
+}
+
+// abandon records that the client awaiting response has given up.
+// If it was the last client waiting for a computation in progress,
+// the computation is cancelled and forgotten.
+func (c *cache[K, V]) abandon(response chan<- result[V]) {
+	e := c.waiting[response]
+	if e == nil {
+		return // already delivered
+	}
+	delete(c.waiting, response)
+	for i, r := range e.waiters {
+		if r == response {
+			e.waiters = append(e.waiters[:i], e.waiters[i+1:]...)
+			break
 		}
-		go e.deliver(req.response)
 	}
+	if len(e.waiters) == 0 {
+		e.cancel()
+		c.remove(e)
+	}
+}
+
+// evict removes the least recently used finished entries
+// until the cache respects MaxEntries.
+func (c *cache[K, V]) evict() {
+	if c.opts.MaxEntries <= 0 {
+		return
+	}
+	for elem := c.lru.Back(); elem != nil && c.lru.Len() > c.opts.MaxEntries; {
+		e := elem.Value.(*entry[K, V])
+		elem = elem.Prev()
+		if e.done {
+			c.remove(e)
+			c.stats.Evictions++
+		}
+	}
+}
+
+// expire sets the expiry time of the newly finished entry e,
+// or removes it at once if it should not be cached.
+func (c *cache[K, V]) expire(e *entry[K, V]) {
+	if e.elem == nil {
+		return // invalidated or abandoned
+	}
+	ttl := c.opts.TTL
+	if e.res.err != nil {
+		switch c.opts.Errors {
+		case ExpireErrors:
+			ttl = c.opts.ErrorTTL
+			if ttl <= 0 {
+				c.remove(e)
+				return
+			}
+		case ForgetErrors:
+			c.remove(e)
+			return
+		}
+	}
+	if ttl > 0 {
+		e.expires = time.Now().Add(ttl)
+	}
+}
+
+// sweepPeriod returns how often to sweep the cache for expired
+// entries: the shortest positive TTL, or zero if none expire.
+func (opts Options) sweepPeriod() time.Duration {
+	period := opts.TTL
+	if opts.Errors == ExpireErrors && opts.ErrorTTL > 0 &&
+		(period == 0 || opts.ErrorTTL < period) {
+		period = opts.ErrorTTL
+	}
+	return period
+}
+
+// expired reports whether e holds a result that is stale at time now.
+func (c *cache[K, V]) expired(e *entry[K, V], now time.Time) bool {
+	return e.done && !e.expires.IsZero() && !now.Before(e.expires)
 }
 
-func (e *entry) call(f Func, key string) {
-	// Evaluate the function.
-	e.res.value, e.res.err = f(key)
-	// Broadcast the ready condition.
-	close(e.ready)
+func (memo *Memo[K, V]) call(ctx context.Context, e *entry[K, V]) {
+	f := e.f
+	if f == nil {
+		f = func(ctx context.Context) (V, error) { return memo.f(ctx, e.key) }
+	}
+	e.res = memo.compute(ctx, e.key, f)
+	// Tell the monitor, which broadcasts the result.
+	memo.done <- e
 }
 
-func (e *entry) deliver(response chan<- result) {
-	// Wait for the ready condition.
-	<-e.ready
-	// Send the result to the client.
-	response <- e.res
+// compute loads the result for key from the store, or evaluates
+// f, retrying with exponential back-off, and stores it.
+// A store that fails is treated as empty.
+func (memo *Memo[K, V]) compute(ctx context.Context, key K, f func(context.Context) (V, error)) result[V] {
+	if memo.store != nil {
+		if v, ok, err := memo.store.Load(key); err == nil && ok {
+			return result[V]{v, nil}
+		}
+	}
+	var res result[V]
+	for tries := 0; ; tries++ {
+		res.value, res.err = f(ctx)
+		if res.err == nil || tries == memo.opts.Retries || ctx.Err() != nil {
+			break
+		}
+		if !sleep(ctx, memo.opts.Backoff<<uint(tries)) {
+			break
+		}
+	}
+	if res.err == nil && memo.store != nil {
+		memo.store.Save(key, res.value) // NOTE: ignoring errors
+	}
+	return res
+}
+
+// sleep waits for duration d, and reports false if ctx
+// is cancelled first.
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
 }
 
 //!-monitor
+
+// A KeyedMemo memoizes a function whose keys need not be comparable,
+// such as slices, or whose equality should differ from ==, such as
+// case-insensitive strings.  A key function maps each key to a
+// comparable value, and keys with the same value share a result.
+// The cache, and any store, are indexed by the key function's value.
+type KeyedMemo[K any, H comparable, V any] struct {
+	f    func(context.Context, K) (V, error)
+	key  func(K) H
+	memo *Memo[H, V]
+}
+
+// NewWithKey returns a memoization of f that identifies keys by key,
+// and whose cache is bounded by opts and backed by store, if not nil.
+// Clients must subsequently call Close.
+func NewWithKey[K any, H comparable, V any](f func(context.Context, K) (V, error), key func(K) H, opts Options, store Store[H, V]) *KeyedMemo[K, H, V] {
+	return &KeyedMemo[K, H, V]{f: f, key: key, memo: NewWithStore[H, V](nil, opts, store)}
+}
+
+// Get is like Memo.Get.
+func (memo *KeyedMemo[K, H, V]) Get(ctx context.Context, key K) (V, error) {
+	return memo.memo.get(ctx, memo.key(key), func(ctx context.Context) (V, error) {
+		return memo.f(ctx, key)
+	})
+}
+
+// Invalidate is like Memo.Invalidate.
+func (memo *KeyedMemo[K, H, V]) Invalidate(key K) error {
+	return memo.memo.Invalidate(memo.key(key))
+}
+
+// Stats is like Memo.Stats.
+func (memo *KeyedMemo[K, H, V]) Stats() Stats { return memo.memo.Stats() }
+
+// Close is like Memo.Close.
+func (memo *KeyedMemo[K, H, V]) Close() { memo.memo.Close() }
@@ -4,22 +4,422 @@
 package memo_test
 
 import (
+	"context"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"gopl.io/ch9/memo5"
 	"gopl.io/ch9/memotest"
 )
 
-var httpGetBody = memotest.HTTPGetBody
+var httpGetBody = memotest.HTTPGetBodyContext
 
 func Test(t *testing.T) {
 	m := memo.New(httpGetBody)
 	defer m.Close()
-	memotest.Sequential(t, m)
+	memotest.Sequential(t, memotest.WithBackground(m), memotest.Bytes)
 }
 
 func TestConcurrent(t *testing.T) {
 	m := memo.New(httpGetBody)
 	defer m.Close()
-	memotest.Concurrent(t, m)
+	memotest.Concurrent(t, memotest.WithBackground(m), memotest.Bytes)
+}
+
+// counter returns a Func that records how often each key is computed,
+// and blocks each computation until release is closed or its
+// context is cancelled.
+func counter(release <-chan struct{}) (memo.Func[string, string], func(key string) int) {
+	var mu sync.Mutex
+	calls := make(map[string]int)
+	f := func(ctx context.Context, key string) (string, error) {
+		mu.Lock()
+		calls[key]++
+		mu.Unlock()
+		select {
+		case <-release:
+			return key + "!", nil
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+	count := func(key string) int {
+		mu.Lock()
+		defer mu.Unlock()
+		return calls[key]
+	}
+	return f, count
+}
+
+var ctx = context.Background()
+
+func TestMaxEntries(t *testing.T) {
+	released := make(chan struct{})
+	close(released)
+	f, calls := counter(released)
+	m := memo.NewWithOptions(f, memo.Options{MaxEntries: 2})
+	defer m.Close()
+
+	for _, key := range []string{"a", "b", "a", "c", "a", "b"} {
+		if v, err := m.Get(ctx, key); v != key+"!" || err != nil {
+			t.Fatalf("Get(%q) = %v, %v", key, v, err)
+		}
+	}
+	// "b" was least recently used when "c" was added.
+	for key, want := range map[string]int{"a": 1, "b": 2, "c": 1} {
+		if got := calls(key); got != want {
+			t.Errorf("%q computed %d times, want %d", key, got, want)
+		}
+	}
+	want := memo.Stats{Hits: 2, Misses: 4, Evictions: 2}
+	if got := m.Stats(); got != want {
+		t.Errorf("Stats = %+v, want %+v", got, want)
+	}
+}
+
+func TestTTL(t *testing.T) {
+	released := make(chan struct{})
+	close(released)
+	f, calls := counter(released)
+	m := memo.NewWithOptions(f, memo.Options{TTL: 50 * time.Millisecond})
+	defer m.Close()
+
+	m.Get(ctx, "a")
+	m.Get(ctx, "a")
+	if got := calls("a"); got != 1 {
+		t.Fatalf("before expiry, computed %d times, want 1", got)
+	}
+	time.Sleep(100 * time.Millisecond)
+	m.Get(ctx, "a")
+	if got := calls("a"); got != 2 {
+		t.Fatalf("after expiry, computed %d times, want 2", got)
+	}
+	if got := m.Stats().Evictions; got != 1 {
+		t.Errorf("Evictions = %d, want 1", got)
+	}
+}
+
+func TestInvalidate(t *testing.T) {
+	release := make(chan struct{})
+	f, calls := counter(release)
+	m := memo.NewWithOptions(f, memo.Options{MaxEntries: 1})
+	defer m.Close()
+
+	// Concurrent requests share one computation, even when the
+	// cache is full of in-progress entries and when the key is
+	// invalidated while it is being computed.
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m.Get(ctx, "a")
+		}()
+	}
+	for m.Stats().Hits+m.Stats().Misses < 10 {
+		time.Sleep(time.Millisecond)
+	}
+	if got := m.Stats().InFlight; got != 1 {
+		t.Errorf("InFlight = %d, want 1", got)
+	}
+	m.Invalidate("a")
+	close(release)
+	wg.Wait()
+	if got := calls("a"); got != 1 {
+		t.Errorf("computed %d times, want 1", got)
+	}
+
+	m.Get(ctx, "a")
+	if got := calls("a"); got != 2 {
+		t.Errorf("after Invalidate, computed %d times, want 2", got)
+	}
+}
+
+func TestCancel(t *testing.T) {
+	release := make(chan struct{})
+	f, calls := counter(release)
+	m := memo.New(f)
+	defer m.Close()
+
+	// Two clients wait for "a"; one gives up.
+	ctx1, cancel1 := context.WithCancel(ctx)
+	errc := make(chan error)
+	go func() {
+		_, err := m.Get(ctx1, "a")
+		errc <- err
+	}()
+	valuec := make(chan string)
+	go func() {
+		v, _ := m.Get(ctx, "a")
+		valuec <- v
+	}()
+	for m.Stats().Hits+m.Stats().Misses < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel1()
+	if err := <-errc; err != context.Canceled {
+		t.Errorf("cancelled Get returned %v, want %v", err, context.Canceled)
+	}
+
+	// The other still gets the shared result.
+	close(release)
+	if v := <-valuec; v != "a!" {
+		t.Errorf("remaining Get = %v, want a!", v)
+	}
+	if got := calls("a"); got != 1 {
+		t.Errorf("computed %d times, want 1", got)
+	}
+}
+
+func TestCancelAll(t *testing.T) {
+	release := make(chan struct{})
+	f, calls := counter(release)
+	m := memo.New(f)
+	defer m.Close()
+
+	// When every client gives up, the computation is
+	// cancelled and its result is not cached.
+	ctx1, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := m.Get(ctx1, "a"); err != context.Canceled {
+				t.Errorf("Get = %v, want %v", err, context.Canceled)
+			}
+		}()
+	}
+	for m.Stats().Hits+m.Stats().Misses < 3 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	wg.Wait()
+	for m.Stats().InFlight > 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	close(release)
+	if v, err := m.Get(ctx, "a"); v != "a!" || err != nil {
+		t.Errorf("Get after cancellation = %v, %v, want a!, nil", v, err)
+	}
+	if got := calls("a"); got != 2 {
+		t.Errorf("computed %d times, want 2", got)
+	}
+
+	if _, err := m.Get(ctx1, "a"); err != context.Canceled {
+		t.Errorf("Get with done context = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestCancelAfterClose(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	f, _ := counter(release)
+	m := memo.New(f)
+
+	// A client may abandon its request after Close.
+	ctx1, cancel := context.WithCancel(ctx)
+	errc := make(chan error)
+	go func() {
+		_, err := m.Get(ctx1, "a")
+		errc <- err
+	}()
+	for m.Stats().Misses < 1 {
+		time.Sleep(time.Millisecond)
+	}
+	m.Close()
+	cancel()
+	if err := <-errc; err != context.Canceled {
+		t.Errorf("Get = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestNoLeak(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	release := make(chan struct{})
+	f, _ := counter(release)
+	m := memo.NewWithOptions(f, memo.Options{TTL: time.Hour})
+	ctx1, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	var wg sync.WaitGroup
+	for _, key := range []string{"a", "b", "a", "c"} {
+		wg.Add(1)
+		go func(key string) {
+			defer wg.Done()
+			m.Get(ctx1, key)
+		}(key)
+	}
+	wg.Wait()
+	m.Close()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for runtime.NumGoroutine() > before {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d goroutines after Close, want %d", runtime.NumGoroutine(), before)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// flaky returns a Func that fails the first n times it is called,
+// and a function reporting how many times it has been called.
+func flaky(n int) (memo.Func[string, int], func() int) {
+	var mu sync.Mutex
+	calls := 0
+	f := func(ctx context.Context, key string) (int, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		if calls <= n {
+			return 0, fmt.Errorf("attempt %d failed", calls)
+		}
+		return calls, nil
+	}
+	count := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return calls
+	}
+	return f, count
+}
+
+func TestErrorPolicy(t *testing.T) {
+	for _, test := range []struct {
+		opts  memo.Options
+		calls int // after two sequential Gets
+	}{
+		{memo.Options{}, 1},
+		{memo.Options{Errors: memo.ForgetErrors}, 2},
+		{memo.Options{Errors: memo.ExpireErrors, ErrorTTL: time.Hour}, 1},
+		{memo.Options{Errors: memo.ExpireErrors}, 2},
+	} {
+		f, calls := flaky(1)
+		m := memo.NewWithOptions(f, test.opts)
+		if _, err := m.Get(ctx, "k"); err == nil {
+			t.Errorf("%+v: first Get succeeded, want error", test.opts)
+		}
+		m.Get(ctx, "k")
+		if got := calls(); got != test.calls {
+			t.Errorf("%+v: computed %d times, want %d", test.opts, got, test.calls)
+		}
+		m.Close()
+	}
+
+	// An expired error is recomputed, but a value is kept.
+	f, calls := flaky(1)
+	m := memo.NewWithOptions(f, memo.Options{Errors: memo.ExpireErrors, ErrorTTL: 20 * time.Millisecond})
+	defer m.Close()
+	m.Get(ctx, "k")
+	time.Sleep(50 * time.Millisecond)
+	if v, err := m.Get(ctx, "k"); v != 2 || err != nil {
+		t.Errorf("Get after error expired = %d, %v, want 2, nil", v, err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	m.Get(ctx, "k")
+	if got := calls(); got != 2 {
+		t.Errorf("computed %d times, want 2", got)
+	}
+}
+
+func TestRetries(t *testing.T) {
+	f, calls := flaky(3)
+	m := memo.NewWithOptions(f, memo.Options{
+		Errors:  memo.ForgetErrors,
+		Retries: 3,
+		Backoff: time.Millisecond,
+	})
+	defer m.Close()
+
+	// Concurrent clients share one attempt, including its retries.
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if v, err := m.Get(ctx, "k"); v != 4 || err != nil {
+				t.Errorf("Get = %d, %v, want 4, nil", v, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if got := calls(); got != 4 {
+		t.Errorf("computed %d times, want 4", got)
+	}
+
+	// Too few retries reports the last error.
+	f, calls = flaky(3)
+	m2 := memo.NewWithOptions(f, memo.Options{Retries: 1})
+	defer m2.Close()
+	if _, err := m2.Get(ctx, "k"); err == nil || err.Error() != "attempt 2 failed" {
+		t.Errorf("Get = %v, want attempt 2 failed", err)
+	}
+	if got := calls(); got != 2 {
+		t.Errorf("computed %d times, want 2", got)
+	}
+}
+
+func TestConcurrentOnce(t *testing.T) {
+	m := memo.New(httpGetBody)
+	defer m.Close()
+	memotest.ConcurrentOnce(t, memotest.WithBackground(m), memotest.Bytes)
+}
+
+func TestTransientFailure(t *testing.T) {
+	f := memotest.NewFixture(t, memotest.Config{Fail: memotest.FailFirst(2)})
+	m := memo.NewWithOptions(httpGetBody, memo.Options{
+		Errors:  memo.ForgetErrors,
+		Retries: 2,
+		Backoff: time.Millisecond,
+	})
+	defer m.Close()
+
+	url := f.URL("/flaky")
+	body, err := m.Get(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != string(memotest.Body("/flaky")) {
+		t.Errorf("Get returned the wrong body")
+	}
+	if got := f.Requests(url); got != 3 {
+		t.Errorf("server received %d requests, want 3", got)
+	}
+}
+
+func BenchmarkLatency(b *testing.B) {
+	m := memo.New(httpGetBody)
+	defer m.Close()
+	memotest.Benchmark(b, memotest.WithBackground(m))
+}
+
+func TestKeyed(t *testing.T) {
+	released := make(chan struct{})
+	close(released)
+	f, calls := counter(released)
+	// Keys that differ only in case share a result.
+	m := memo.NewWithKey[string, string, string](f, strings.ToLower, memo.Options{}, nil)
+	defer m.Close()
+	for _, key := range []string{"Go", "GO", "go", "C"} {
+		v, err := m.Get(ctx, key)
+		if err != nil || strings.ToLower(v) != strings.ToLower(key)+"!" {
+			t.Errorf("Get(%q) = %q, %v", key, v, err)
+		}
+	}
+	if got := calls("Go") + calls("GO") + calls("go"); got != 1 {
+		t.Errorf("computed Go %d times, want 1", got)
+	}
+	m.Invalidate("gO")
+	m.Get(ctx, "go")
+	if got := calls("Go") + calls("go"); got != 2 {
+		t.Errorf("after Invalidate, computed Go %d times, want 2", got)
+	}
+	if got := m.Stats(); got.Hits != 2 || got.Misses != 3 {
+		t.Errorf("Stats = %+v, want 2 hits and 3 misses", got)
+	}
 }
@@ -0,0 +1,256 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package memo
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// A Store holds results beyond the lifetime of a Memo's cache.  On a
+// cache miss, a Memo loads the result from its store if present, and
+// otherwise saves a successfully computed result there.  Errors are
+// never stored, and stored results do not expire.
+//
+// A Store must be safe for concurrent use.
+type Store[K comparable, V any] interface {
+	// Load returns the value stored for key, and reports
+	// whether there was one.
+	Load(key K) (value V, ok bool, err error)
+	// Save stores the value for key, replacing any other.
+	Save(key K, value V) error
+	// Delete removes any value stored for key.
+	Delete(key K) error
+}
+
+// A MemoryStore is a Store that holds results in memory.
+type MemoryStore[K comparable, V any] struct {
+	mu     sync.Mutex // guards values
+	values map[K]V
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore[K comparable, V any]() *MemoryStore[K, V] {
+	return &MemoryStore[K, V]{values: make(map[K]V)}
+}
+
+func (s *MemoryStore[K, V]) Load(key K) (V, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.values[key]
+	return v, ok, nil
+}
+
+func (s *MemoryStore[K, V]) Save(key K, value V) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[key] = value
+	return nil
+}
+
+func (s *MemoryStore[K, V]) Delete(key K) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.values, key)
+	return nil
+}
+
+// A Codec converts keys and values to and from bytes for a FileStore.
+// The functions have the signatures of gopl.io/ch12/sexpr's Marshal
+// and Unmarshal, so Codec{sexpr.Marshal, sexpr.Unmarshal} is a Codec.
+type Codec struct {
+	Marshal   func(v interface{}) ([]byte, error)
+	Unmarshal func(data []byte, v interface{}) error
+}
+
+// Gob is a Codec that uses encoding/gob.  Each record carries its
+// own type information, so the log can be read without context.
+var Gob = Codec{
+	Marshal: func(v interface{}) ([]byte, error) {
+		var buf bytes.Buffer
+		err := gob.NewEncoder(&buf).Encode(v)
+		return buf.Bytes(), err
+	},
+	Unmarshal: func(data []byte, v interface{}) error {
+		return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+	},
+}
+
+// A FileStore is a Store that holds results in a file, which is an
+// append-only log of records, each an encoded record preceded by its
+// length as a uvarint.  An in-memory index maps each key to its most
+// recent record.  The log is never compacted.
+type FileStore[K comparable, V any] struct {
+	codec Codec
+
+	mu    sync.Mutex // guards the following
+	file  *os.File
+	size  int64        // length of the log
+	index map[K]extent // location of each key's latest record
+}
+
+// A record is an entry in a FileStore's log.
+// Value holds one element, or none for a deletion.
+type record[K comparable, V any] struct {
+	Key   K
+	Value []V
+}
+
+// An extent is the location of an encoded record in the log.
+type extent struct{ off, n int64 }
+
+// OpenFileStore opens the store held in the named file, creating it
+// if necessary, and builds its index.  A partial record at the end of
+// the log, left by a crash, is discarded.  Clients must call Close.
+func OpenFileStore[K comparable, V any](name string, codec Codec) (*FileStore[K, V], error) {
+	file, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE, 0666)
+	if err != nil {
+		return nil, err
+	}
+	s := &FileStore[K, V]{codec: codec, file: file, index: make(map[K]extent)}
+	if err := s.scan(); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	return s, nil
+}
+
+// scan reads the log to build the index, and truncates any partial
+// record at its end.
+func (s *FileStore[K, V]) scan() error {
+	info, err := s.file.Stat()
+	if err != nil {
+		return err
+	}
+	in := bufio.NewReader(io.NewSectionReader(s.file, 0, info.Size()))
+	var off int64
+	for {
+		n, err := binary.ReadUvarint(in)
+		if err == io.EOF {
+			break
+		}
+		start := off + int64(uvarintLen(n))
+		if err == io.ErrUnexpectedEOF || start+int64(n) > info.Size() {
+			break // partial record
+		} else if err != nil {
+			return err
+		}
+		data := make([]byte, n)
+		if _, err := io.ReadFull(in, data); err != nil {
+			return err
+		}
+		var rec record[K, V]
+		if err := s.codec.Unmarshal(data, &rec); err != nil {
+			return fmt.Errorf("record at offset %d: %v", off, err)
+		}
+		if len(rec.Value) == 0 {
+			delete(s.index, rec.Key)
+		} else {
+			s.index[rec.Key] = extent{start, int64(n)}
+		}
+		off = start + int64(n)
+	}
+	s.size = off
+	return s.file.Truncate(off)
+}
+
+func (s *FileStore[K, V]) Load(key K) (V, bool, error) {
+	var zero V
+	s.mu.Lock()
+	file := s.file
+	x, ok := s.index[key]
+	s.mu.Unlock()
+	if file == nil {
+		return zero, false, os.ErrClosed
+	}
+	if !ok {
+		return zero, false, nil
+	}
+	// The log is append-only, so the record can be
+	// read without holding the lock.
+	data := make([]byte, x.n)
+	if _, err := file.ReadAt(data, x.off); err != nil {
+		return zero, false, err
+	}
+	var rec record[K, V]
+	if err := s.codec.Unmarshal(data, &rec); err != nil {
+		return zero, false, err
+	}
+	if len(rec.Value) != 1 {
+		return zero, false, errors.New("corrupt record")
+	}
+	return rec.Value[0], true, nil
+}
+
+func (s *FileStore[K, V]) Save(key K, value V) error {
+	return s.append(record[K, V]{Key: key, Value: []V{value}})
+}
+
+func (s *FileStore[K, V]) Delete(key K) error {
+	s.mu.Lock()
+	_, ok := s.index[key]
+	s.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	return s.append(record[K, V]{Key: key})
+}
+
+// append writes rec to the end of the log and updates the index.
+func (s *FileStore[K, V]) append(rec record[K, V]) error {
+	data, err := s.codec.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	buf := make([]byte, binary.MaxVarintLen64, binary.MaxVarintLen64+len(data))
+	buf = append(buf[:binary.PutUvarint(buf, uint64(len(data)))], data...)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return os.ErrClosed
+	}
+	if _, err := s.file.WriteAt(buf, s.size); err != nil {
+		// Discard any partial write, so the next record
+		// doesn't follow garbage.
+		s.file.Truncate(s.size)
+		return err
+	}
+	start := s.size + int64(len(buf)-len(data))
+	s.size += int64(len(buf))
+	if len(rec.Value) == 0 {
+		delete(s.index, rec.Key)
+	} else {
+		s.index[rec.Key] = extent{start, int64(len(data))}
+	}
+	return nil
+}
+
+// Close flushes the log to disk and closes it.
+func (s *FileStore[K, V]) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return os.ErrClosed
+	}
+	err := s.file.Sync()
+	if cerr := s.file.Close(); err == nil {
+		err = cerr
+	}
+	s.file = nil
+	return err
+}
+
+// uvarintLen returns the length of the uvarint encoding of x.
+func uvarintLen(x uint64) int {
+	var buf [binary.MaxVarintLen64]byte
+	return binary.PutUvarint(buf[:], x)
+}
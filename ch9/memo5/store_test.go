@@ -0,0 +1,125 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package memo_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gopl.io/ch12/sexpr"
+	"gopl.io/ch9/memo5"
+)
+
+type point struct{ X, Y int }
+
+func TestFileStore(t *testing.T) {
+	for _, codec := range []struct {
+		name string
+		memo.Codec
+	}{
+		{"gob", memo.Gob},
+		{"sexpr", memo.Codec{Marshal: sexpr.Marshal, Unmarshal: sexpr.Unmarshal}},
+	} {
+		name := filepath.Join(t.TempDir(), "log")
+		s, err := memo.OpenFileStore[point, string](name, codec.Codec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, p := range []point{{1, 2}, {3, 4}, {5, 6}} {
+			if err := s.Save(p, "first"); err != nil {
+				t.Fatal(err)
+			}
+		}
+		s.Save(point{3, 4}, "second")
+		s.Delete(point{5, 6})
+		if err := s.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		// Simulate a crash part way through appending a record.
+		f, err := os.OpenFile(name, os.O_WRONLY|os.O_APPEND, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte{100, 1, 2})
+		f.Close()
+
+		s, err = memo.OpenFileStore[point, string](name, codec.Codec)
+		if err != nil {
+			t.Fatalf("%s: reopen: %v", codec.name, err)
+		}
+		for p, want := range map[point]string{{1, 2}: "first", {3, 4}: "second", {5, 6}: ""} {
+			v, ok, err := s.Load(p)
+			if err != nil || v != want || ok != (want != "") {
+				t.Errorf("%s: Load(%v) = %q, %t, %v, want %q", codec.name, p, v, ok, err, want)
+			}
+		}
+		// Records appended after recovery are readable too.
+		s.Save(point{7, 8}, "third")
+		if v, _, _ := s.Load(point{7, 8}); v != "third" {
+			t.Errorf("%s: Load after recovery = %q, want third", codec.name, v)
+		}
+		s.Close()
+		if _, _, err := s.Load(point{1, 2}); err != os.ErrClosed {
+			t.Errorf("%s: Load after Close = %v, want %v", codec.name, err, os.ErrClosed)
+		}
+	}
+}
+
+func TestMemoStore(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "log")
+	released := make(chan struct{})
+	close(released)
+
+	// Each run of the memo is like one deploy of a server.
+	run := func(keys ...string) (calls int) {
+		f, count := counter(released)
+		store, err := memo.OpenFileStore[string, string](name, memo.Gob)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer store.Close()
+		m := memo.NewWithStore[string, string](f, memo.Options{}, store)
+		defer m.Close()
+		for _, key := range keys {
+			if v, err := m.Get(ctx, key); v != key+"!" || err != nil {
+				t.Errorf("Get(%q) = %q, %v", key, v, err)
+			}
+		}
+		if err := m.Invalidate("c"); err != nil {
+			t.Error(err)
+		}
+		for _, key := range keys {
+			calls += count(key)
+		}
+		return calls
+	}
+	if got := run("a", "b", "c"); got != 3 {
+		t.Errorf("first run computed %d results, want 3", got)
+	}
+	if got := run("a", "b", "c"); got != 1 {
+		t.Errorf("second run computed %d results, want 1", got)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	released := make(chan struct{})
+	close(released)
+	f, calls := counter(released)
+	store := memo.NewMemoryStore[string, string]()
+	m := memo.NewWithStore[string, string](f, memo.Options{MaxEntries: 1}, store)
+	defer m.Close()
+
+	// Results evicted from the cache are reloaded from the store.
+	for _, key := range []string{"a", "b", "a", "b"} {
+		m.Get(ctx, key)
+	}
+	if calls("a") != 1 || calls("b") != 1 {
+		t.Errorf("computed a %d times and b %d times, want 1 each", calls("a"), calls("b"))
+	}
+	if got := m.Stats().Evictions; got != 3 {
+		t.Errorf("Evictions = %d, want 3", got)
+	}
+}
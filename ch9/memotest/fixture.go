@@ -0,0 +1,166 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+package memotest
+
+import (
+	"bytes"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Config configures a Fixture.
+type Config struct {
+	// Latency is how long the server takes to respond.
+	Latency time.Duration
+
+	// Fail, if not nil, reports whether the server should fail
+	// the nth request (counting from 1) for the given path, by
+	// closing the connection without responding.
+	Fail func(path string, n int) bool
+}
+
+// FailFirst returns a Fail function that fails the first n
+// requests for every path.
+func FailFirst(n int) func(path string, i int) bool {
+	return func(path string, i int) bool { return i <= n }
+}
+
+// A Fixture is a local HTTP server that stands in for the web sites
+// fetched by the tests, so they can run offline and deterministically.
+// It serves every path, responding with a body whose content and
+// length depend only on the path, and counts the requests for each.
+type Fixture struct {
+	cfg    Config
+	server *httptest.Server
+
+	mu       sync.Mutex // guards requests
+	requests map[string]int
+}
+
+// NewFixture starts a Fixture, which is closed when tb's test ends.
+func NewFixture(tb testing.TB, cfg Config) *Fixture {
+	f := &Fixture{cfg: cfg, requests: make(map[string]int)}
+	f.server = httptest.NewServer(http.HandlerFunc(f.serve))
+	tb.Cleanup(f.server.Close)
+	return f
+}
+
+func (f *Fixture) serve(w http.ResponseWriter, req *http.Request) {
+	f.mu.Lock()
+	f.requests[req.URL.Path]++
+	n := f.requests[req.URL.Path]
+	f.mu.Unlock()
+
+	select {
+	case <-time.After(f.cfg.Latency):
+	case <-req.Context().Done():
+		return
+	}
+	if f.cfg.Fail != nil && f.cfg.Fail(req.URL.Path, n) {
+		if conn, _, err := w.(http.Hijacker).Hijack(); err == nil {
+			conn.Close()
+		}
+		return
+	}
+	w.Write(Body(req.URL.Path))
+}
+
+// URL returns the URL of path on the fixture's server.
+func (f *Fixture) URL(path string) string {
+	return f.server.URL + path
+}
+
+// Requests returns the number of requests the server has received
+// for the URL, which must belong to the fixture.
+func (f *Fixture) Requests(url string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.requests[url[len(f.server.URL):]]
+}
+
+// Body returns the body that a Fixture serves for path: a few
+// kilobytes of text that depend only on the path.
+func Body(path string) []byte {
+	h := fnv.New64a()
+	h.Write([]byte(path))
+	n := 1024 + h.Sum64()%8192
+	return bytes.Repeat([]byte(path), int(n)/len(path)+1)[:n]
+}
+
+// Latency summarizes the distribution of request latencies.
+type Latency struct {
+	P50, P90, P99, Max time.Duration
+}
+
+// Load issues n requests to m from the given number of concurrent
+// clients, and returns the distribution of their latencies.  The
+// keys are drawn from 100 paths on the fixture, in a pseudo-random
+// order that is the same for every call, so that implementations
+// can be compared.  It reports an error to tb if any request fails.
+func Load[V any](tb testing.TB, f *Fixture, m M[V], n, clients int) Latency {
+	rng := rand.New(rand.NewSource(1))
+	keys := make(chan string, n)
+	for i := 0; i < n; i++ {
+		keys <- f.URL(fmt.Sprintf("/%d", rng.Intn(100)))
+	}
+	close(keys)
+
+	latencies := make([]time.Duration, 0, n)
+	var mu sync.Mutex // guards latencies
+	var wg sync.WaitGroup
+	for i := 0; i < clients; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for url := range keys {
+				start := time.Now()
+				if _, err := m.Get(url); err != nil {
+					tb.Error(err)
+				}
+				d := time.Since(start)
+				mu.Lock()
+				latencies = append(latencies, d)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(latencies) == 0 {
+		return Latency{}
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	at := func(p float64) time.Duration {
+		return latencies[int(p*float64(len(latencies)-1))]
+	}
+	return Latency{at(0.50), at(0.90), at(0.99), latencies[len(latencies)-1]}
+}
+
+// Benchmark measures the latency of b.N requests to m, from 8
+// concurrent clients of a fixture whose server takes a millisecond
+// to respond, and reports its percentiles as metrics.
+func Benchmark[V any](b *testing.B, m M[V]) {
+	f := NewFixture(b, Config{Latency: time.Millisecond})
+	b.ResetTimer()
+	lat := Load(b, f, m, b.N, 8)
+	b.StopTimer()
+	for _, metric := range []struct {
+		d    time.Duration
+		unit string
+	}{
+		{lat.P50, "p50-µs"},
+		{lat.P90, "p90-µs"},
+		{lat.P99, "p99-µs"},
+		{lat.Max, "max-µs"},
+	} {
+		b.ReportMetric(float64(metric.d.Microseconds()), metric.unit)
+	}
+}
@@ -8,6 +8,8 @@
 package memotest
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"io/ioutil"
 	"log"
@@ -31,37 +33,91 @@ func httpGetBody(url string) (interface{}, error) {
 
 var HTTPGetBody = httpGetBody
 
-func incomingURLs() <-chan string {
-	ch := make(chan string)
-	go func() {
-		for _, url := range []string{
-			"https://golang.org",
-			"https://godoc.org",
-			"https://play.golang.org",
-			"http://gopl.io",
-			"https://golang.org",
-			"https://godoc.org",
-			"https://play.golang.org",
-			"http://gopl.io",
-		} {
-			ch <- url
-		}
-		close(ch)
-	}()
-	return ch
+// HTTPGetBodyBytes is like HTTPGetBody, but its result type
+// is []byte, for use with typed memos.
+func HTTPGetBodyBytes(url string) ([]byte, error) {
+	return HTTPGetBodyContext(context.Background(), url)
+}
+
+// HTTPGetBodyContext is like HTTPGetBodyBytes, but abandons the
+// request if ctx is cancelled.
+func HTTPGetBodyContext(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return ioutil.ReadAll(resp.Body)
+}
+
+// incomingURLs returns a function that, like the book's incomingURLs,
+// yields a stream of URLs with repeats, but on a fixture server
+// rather than on real web sites.
+func incomingURLs(f *Fixture) func() <-chan string {
+	return func() <-chan string {
+		ch := make(chan string)
+		go func() {
+			for _, path := range []string{
+				"/golang.org",
+				"/godoc.org",
+				"/play.golang.org",
+				"/gopl.io",
+				"/golang.org",
+				"/godoc.org",
+				"/play.golang.org",
+				"/gopl.io",
+			} {
+				ch <- f.URL(path)
+			}
+			close(ch)
+		}()
+		return ch
+	}
 }
 
-type M interface {
-	Get(key string) (interface{}, error)
+// An M is a memo whose values have type V: interface{} for the
+// book's designs, or a specific type such as []byte for typed ones.
+type M[V any] interface {
+	Get(key string) (V, error)
 }
 
+// A ContextM is a memo whose Get accepts a context.
+type ContextM[V any] interface {
+	Get(ctx context.Context, key string) (V, error)
+}
+
+// WithBackground adapts m to the M interface by calling its
+// Get method with a background context.
+func WithBackground[V any](m ContextM[V]) M[V] { return background[V]{m} }
+
+type background[V any] struct{ m ContextM[V] }
+
+func (b background[V]) Get(key string) (V, error) {
+	return b.m.Get(context.Background(), key)
+}
+
+// AnyBody returns the body held in a value returned by HTTPGetBody,
+// for memos whose values have type interface{}.
+func AnyBody(value interface{}) []byte { return value.([]byte) }
+
+// Bytes returns its argument, for memos whose values
+// have type []byte.
+func Bytes(value []byte) []byte { return value }
+
 /*
 //!+seq
 	m := memo.New(httpGetBody)
 //!-seq
 */
 
-func Sequential(t *testing.T, m M) {
+// Sequential fetches a stream of URLs through m one at a time.
+// The body function returns the body held in a value of m.
+func Sequential[V any](t *testing.T, m M[V], body func(V) []byte) {
+	incomingURLs := incomingURLs(NewFixture(t, Config{Latency: 10 * time.Millisecond}))
 	//!+seq
 	for url := range incomingURLs() {
 		start := time.Now()
@@ -71,7 +127,7 @@ func Sequential(t *testing.T, m M) {
 			continue
 		}
 		fmt.Printf("%s, %s, %d bytes\n",
-			url, time.Since(start), len(value.([]byte)))
+			url, time.Since(start), len(body(value)))
 	}
 	//!-seq
 }
@@ -82,7 +138,10 @@ func Sequential(t *testing.T, m M) {
 //!-conc
 */
 
-func Concurrent(t *testing.T, m M) {
+// Concurrent fetches a stream of URLs through m all at once.
+// The body function returns the body held in a value of m.
+func Concurrent[V any](t *testing.T, m M[V], body func(V) []byte) {
+	incomingURLs := incomingURLs(NewFixture(t, Config{Latency: 10 * time.Millisecond}))
 	//!+conc
 	var n sync.WaitGroup
 	for url := range incomingURLs() {
@@ -96,9 +155,41 @@ func Concurrent(t *testing.T, m M) {
 				return
 			}
 			fmt.Printf("%s, %s, %d bytes\n",
-				url, time.Since(start), len(value.([]byte)))
+				url, time.Since(start), len(body(value)))
 		}(url)
 	}
 	n.Wait()
 	//!-conc
 }
+
+// ConcurrentOnce is like Concurrent, but requests each URL many
+// times at once and reports an error unless the memo fetched
+// each exactly once and returned the right body.  Designs without
+// duplicate suppression fail.
+func ConcurrentOnce[V any](t *testing.T, m M[V], body func(V) []byte) {
+	f := NewFixture(t, Config{Latency: 10 * time.Millisecond})
+	paths := []string{"/golang.org", "/godoc.org", "/play.golang.org", "/gopl.io"}
+	var n sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		for _, path := range paths {
+			n.Add(1)
+			go func(path string) {
+				defer n.Done()
+				value, err := m.Get(f.URL(path))
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if !bytes.Equal(body(value), Body(path)) {
+					t.Errorf("Get(%s) returned the wrong body", path)
+				}
+			}(path)
+		}
+	}
+	n.Wait()
+	for _, path := range paths {
+		if got := f.Requests(f.URL(path)); got != 1 {
+			t.Errorf("%s fetched %d times, want 1", path, got)
+		}
+	}
+}
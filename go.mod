@@ -1,6 +1,6 @@
 module gopl.io
 
-go 1.16
+go 1.21
 
 replace (
 	gopl.io/ch1 => ./ch1